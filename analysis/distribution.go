@@ -0,0 +1,154 @@
+package analysis
+
+import (
+	"eusurveymgr/db"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Frequency is one row of a choice question's frequency table. Percent is
+// relative to the respondents who answered the question.
+type Frequency struct {
+	Label   string  `json:"label"`
+	Count   int     `json:"count"`
+	Percent float64 `json:"percent"`
+}
+
+// Distribution summarises the answers to one question.
+type Distribution struct {
+	QuestionUID    string       `json:"question_uid"`
+	MatrixUID      string       `json:"matrix_uid,omitempty"`
+	Question       string       `json:"question"`
+	Type           string       `json:"type"`
+	Kind           Kind         `json:"kind"`
+	Respondents    int          `json:"respondents"`
+	Answered       int          `json:"answered"`
+	Missing        int          `json:"missing"`
+	MissingPercent float64      `json:"missing_percent"`
+	Frequencies    []Frequency  `json:"frequencies,omitempty"`
+	Numeric        *Descriptive `json:"numeric,omitempty"`
+	Invalid        int          `json:"invalid,omitempty"`
+	Length         *Descriptive `json:"length,omitempty"`
+}
+
+// Distributions computes a Distribution for every answerable question, or
+// only for questionUID when it is non-empty (a matrix UID selects its rows).
+func Distributions(d *Dataset, questionUID string) []Distribution {
+	grouped := d.answersByQuestion()
+	respondents := len(d.AnswerSets)
+
+	var result []Distribution
+	for _, q := range d.Questions() {
+		if questionUID != "" && q.UID != questionUID && q.Matrix != questionUID {
+			continue
+		}
+		dist := Distribution{
+			QuestionUID: q.UID,
+			MatrixUID:   q.Matrix,
+			Question:    q.Title,
+			Type:        q.Type,
+			Kind:        q.Kind,
+			Respondents: respondents,
+		}
+
+		bySet := grouped[q.UID]
+		for _, rows := range bySet {
+			if hasValue(rows) {
+				dist.Answered++
+			}
+		}
+		dist.Missing = respondents - dist.Answered
+		if dist.Missing < 0 {
+			dist.Missing = 0
+		}
+		dist.MissingPercent = percent(dist.Missing, respondents)
+
+		switch q.Kind {
+		case KindChoice:
+			dist.Frequencies = frequencies(q, bySet, dist.Answered)
+		case KindNumeric:
+			var values []float64
+			for _, rows := range bySet {
+				for _, r := range rows {
+					v := strings.TrimSpace(r.Value.String)
+					if v == "" {
+						continue
+					}
+					f, err := strconv.ParseFloat(strings.Replace(v, ",", ".", 1), 64)
+					if err != nil {
+						dist.Invalid++
+						continue
+					}
+					values = append(values, f)
+				}
+			}
+			stats := Describe(values)
+			dist.Numeric = &stats
+		case KindText:
+			var lengths []float64
+			for _, rows := range bySet {
+				for _, r := range rows {
+					v := strings.TrimSpace(r.Value.String)
+					if v == "" {
+						continue
+					}
+					lengths = append(lengths, float64(utf8.RuneCountInString(v)))
+				}
+			}
+			stats := Describe(lengths)
+			dist.Length = &stats
+		}
+		result = append(result, dist)
+	}
+	return result
+}
+
+// frequencies counts answers per option. Options are listed in survey order
+// (including unselected ones); values that match no known option are appended
+// in the order they are first seen.
+func frequencies(q Question, bySet map[int64][]db.AnswerRow, answered int) []Frequency {
+	labels := make(map[string]string, len(q.Options))
+	var order []string
+	counts := make(map[string]int)
+	for _, o := range q.Options {
+		labels[o.UID] = o.Label
+		order = append(order, o.UID)
+	}
+
+	for _, id := range sortedKeys(bySet) {
+		seen := make(map[string]bool)
+		for _, r := range bySet[id] {
+			key := r.PA_UID.String
+			if key == "" {
+				key = "value:" + strings.TrimSpace(r.Value.String)
+				if key == "value:" {
+					continue
+				}
+			}
+			if _, ok := labels[key]; !ok {
+				label := strings.TrimSpace(r.Value.String)
+				if label == "" {
+					label = key
+				}
+				labels[key] = label
+				order = append(order, key)
+			}
+			// Count each option at most once per respondent.
+			if !seen[key] {
+				seen[key] = true
+				counts[key]++
+			}
+		}
+	}
+
+	freqs := make([]Frequency, 0, len(order))
+	for _, key := range order {
+		freqs = append(freqs, Frequency{
+			Label:   labels[key],
+			Count:   counts[key],
+			Percent: percent(counts[key], answered),
+		})
+	}
+	return freqs
+}
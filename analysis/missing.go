@@ -0,0 +1,91 @@
+package analysis
+
+// Item identifies a question in a completeness report. The rows of a matrix
+// are items of their own, with MatrixUID set.
+type Item struct {
+	UID       string `json:"uid"`
+	Title     string `json:"title"`
+	Optional  bool   `json:"optional"`
+	MatrixUID string `json:"matrix_uid,omitempty"`
+}
+
+// Respondent is one row of the respondent × question completeness matrix.
+type Respondent struct {
+	AnswerSetID     int64   `json:"answer_set_id"`
+	UniqueCode      string  `json:"uniquecode"`
+	Name            string  `json:"name"`
+	Email           string  `json:"email"`
+	Answered        int     `json:"answered"`
+	Total           int     `json:"total"`
+	Completeness    float64 `json:"completeness"`
+	BelowThreshold  bool    `json:"below_threshold"`
+	MissingRequired []Item  `json:"missing_required"`
+	MissingOptional []Item  `json:"missing_optional"`
+	// Matrix holds one entry per report item, true when answered.
+	Matrix []bool `json:"matrix"`
+}
+
+// SkipRate is the per-question share of respondents who left it empty.
+type SkipRate struct {
+	Item
+	Answered int     `json:"answered"`
+	Skipped  int     `json:"skipped"`
+	Rate     float64 `json:"skip_rate"`
+}
+
+// MissingReport is the completeness picture of a survey. Completeness and
+// skip rates are percentages.
+type MissingReport struct {
+	Threshold   float64      `json:"threshold"`
+	Items       []Item       `json:"items"`
+	Respondents []Respondent `json:"respondents"`
+	SkipRates   []SkipRate   `json:"skip_rates"`
+}
+
+// Missing builds a completeness report. Respondents whose completeness is
+// below threshold (a percentage) are flagged.
+func Missing(d *Dataset, threshold float64) *MissingReport {
+	grouped := d.answersByQuestion()
+	report := &MissingReport{Threshold: threshold}
+
+	for _, q := range d.Questions() {
+		report.Items = append(report.Items, Item{UID: q.UID, Title: q.Title, Optional: q.Optional, MatrixUID: q.Matrix})
+	}
+	report.SkipRates = make([]SkipRate, len(report.Items))
+	for i, item := range report.Items {
+		report.SkipRates[i].Item = item
+	}
+
+	for _, as := range d.AnswerSets {
+		r := Respondent{
+			AnswerSetID: as.AnswerSetID,
+			UniqueCode:  as.UniqueCode,
+			Name:        as.Name.String,
+			Email:       as.Email.String,
+			Total:       len(report.Items),
+			Matrix:      make([]bool, len(report.Items)),
+		}
+		for i, item := range report.Items {
+			if hasValue(grouped[item.UID][as.AnswerSetID]) {
+				r.Matrix[i] = true
+				r.Answered++
+				report.SkipRates[i].Answered++
+				continue
+			}
+			report.SkipRates[i].Skipped++
+			if item.Optional {
+				r.MissingOptional = append(r.MissingOptional, item)
+			} else {
+				r.MissingRequired = append(r.MissingRequired, item)
+			}
+		}
+		r.Completeness = percent(r.Answered, r.Total)
+		r.BelowThreshold = r.Completeness < threshold
+		report.Respondents = append(report.Respondents, r)
+	}
+
+	for i := range report.SkipRates {
+		report.SkipRates[i].Rate = percent(report.SkipRates[i].Skipped, len(d.AnswerSets))
+	}
+	return report
+}
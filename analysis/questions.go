@@ -0,0 +1,176 @@
+package analysis
+
+import (
+	"eusurveymgr/db"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kind classifies a survey element by how its answers are summarised.
+type Kind string
+
+const (
+	KindChoice  Kind = "choice"
+	KindNumeric Kind = "numeric"
+	KindText    Kind = "text"
+	KindNone    Kind = ""
+)
+
+// KindOf maps an EUSurvey ELEMENTS.ETYPE value to a Kind. Layout elements
+// (sections, text blocks, images) have no answers and map to KindNone.
+func KindOf(etype string) Kind {
+	switch strings.ToUpper(etype) {
+	case "SINGLECHOICE", "MULTIPLECHOICE", "RATING", "MATRIX", "RANKING":
+		return KindChoice
+	case "NUMBER", "FORMULA":
+		return KindNumeric
+	case "FREETEXT", "EMAIL", "REGEX", "DATE", "TIME":
+		return KindText
+	default:
+		return KindNone
+	}
+}
+
+// Dataset holds the rows needed to analyse one survey.
+type Dataset struct {
+	Elements   []db.ElementRow
+	Options    []db.OptionRow
+	MatrixRows []db.MatrixRow
+	Answers    []db.AnswerRow
+	AnswerSets []db.AnswerSetRow
+}
+
+// LoadDataset reads the elements, options, matrix rows, answer sets and
+// answers of a survey from the repository.
+func LoadDataset(repo db.SurveyRepository, surveyID int64) (*Dataset, error) {
+	var d Dataset
+	var err error
+	if d.Elements, err = repo.ListElements(surveyID); err != nil {
+		return nil, err
+	}
+	if d.Options, err = repo.ListPossibleAnswers(surveyID); err != nil {
+		return nil, err
+	}
+	if d.MatrixRows, err = repo.ListMatrixRows(surveyID); err != nil {
+		return nil, err
+	}
+	if d.AnswerSets, err = repo.ListAnswerSets(surveyID); err != nil {
+		return nil, err
+	}
+	if d.Answers, err = repo.ListSurveyAnswers(surveyID); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Question is an answerable survey element with its option labels. The rows
+// of a matrix are questions of their own, answered under their UID; Matrix is
+// then the UID of the matrix.
+type Question struct {
+	UID      string
+	Title    string
+	Type     string
+	Kind     Kind
+	Optional bool
+	Options  []Option
+	Matrix   string
+}
+
+// Option is a possible answer of a choice question.
+type Option struct {
+	UID   string
+	Label string
+}
+
+// Questions returns the answerable elements of the dataset in display order.
+// A matrix is replaced by its rows, titled "matrix: row", with the matrix's
+// other children (its columns) as options; a matrix without known rows is
+// kept as one question.
+func (d *Dataset) Questions() []Question {
+	rows := make(map[string][]db.MatrixRow)
+	isRow := make(map[string]bool)
+	for _, r := range d.MatrixRows {
+		rows[r.MatrixUID] = append(rows[r.MatrixUID], r)
+		isRow[r.UID] = true
+	}
+	options := make(map[string][]Option)
+	for _, o := range d.Options {
+		if isRow[o.UID] {
+			continue
+		}
+		options[o.QuestionUID] = append(options[o.QuestionUID], Option{UID: o.UID, Label: PlainText(o.Title.String)})
+	}
+
+	var questions []Question
+	for _, e := range d.Elements {
+		kind := KindOf(e.Type)
+		if kind == KindNone {
+			continue
+		}
+		q := Question{
+			UID:      e.UID,
+			Title:    PlainText(e.Title.String),
+			Type:     e.Type,
+			Kind:     kind,
+			Optional: e.Optional,
+			Options:  options[e.UID],
+		}
+		if len(rows[e.UID]) == 0 {
+			questions = append(questions, q)
+			continue
+		}
+		for _, r := range rows[e.UID] {
+			row := q
+			row.UID = r.UID
+			row.Title = q.Title + ": " + PlainText(r.Title.String)
+			row.Matrix = e.UID
+			questions = append(questions, row)
+		}
+	}
+	return questions
+}
+
+// answersByQuestion groups answer rows by question UID and answer set ID.
+func (d *Dataset) answersByQuestion() map[string]map[int64][]db.AnswerRow {
+	grouped := make(map[string]map[int64][]db.AnswerRow)
+	for _, a := range d.Answers {
+		bySet, ok := grouped[a.QuestionUID]
+		if !ok {
+			bySet = make(map[int64][]db.AnswerRow)
+			grouped[a.QuestionUID] = bySet
+		}
+		bySet[a.AnswerSetID] = append(bySet[a.AnswerSetID], a)
+	}
+	return grouped
+}
+
+// hasValue reports whether any of the rows holds a non-empty answer.
+func hasValue(rows []db.AnswerRow) bool {
+	for _, r := range rows {
+		if r.PA_UID.String != "" || strings.TrimSpace(r.Value.String) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedKeys returns the answer set IDs of a grouping in ascending order.
+func sortedKeys(bySet map[int64][]db.AnswerRow) []int64 {
+	keys := make([]int64, 0, len(bySet))
+	for id := range bySet {
+		keys = append(keys, id)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
+
+var tagRe = regexp.MustCompile(`<[^>]*>`)
+
+// PlainText strips the HTML markup EUSurvey stores in element titles.
+func PlainText(s string) string {
+	s = tagRe.ReplaceAllString(s, " ")
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,60 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+)
+
+// Descriptive holds summary statistics for a set of numeric values.
+type Descriptive struct {
+	N      int     `json:"n"`
+	Min    float64 `json:"min"`
+	Max    float64 `json:"max"`
+	Mean   float64 `json:"mean"`
+	Median float64 `json:"median"`
+	StdDev float64 `json:"stdev"`
+}
+
+// Describe computes descriptive statistics. StdDev is the sample standard
+// deviation and is zero for fewer than two values.
+func Describe(values []float64) Descriptive {
+	d := Descriptive{N: len(values)}
+	if len(values) == 0 {
+		return d
+	}
+
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	d.Min = sorted[0]
+	d.Max = sorted[len(sorted)-1]
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	d.Mean = sum / float64(len(sorted))
+
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		d.Median = (sorted[mid-1] + sorted[mid]) / 2
+	} else {
+		d.Median = sorted[mid]
+	}
+
+	if len(sorted) > 1 {
+		var sq float64
+		for _, v := range sorted {
+			sq += (v - d.Mean) * (v - d.Mean)
+		}
+		d.StdDev = math.Sqrt(sq / float64(len(sorted)-1))
+	}
+	return d
+}
+
+// percent returns part/whole as a percentage, or 0 when whole is 0.
+func percent(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) * 100 / float64(whole)
+}
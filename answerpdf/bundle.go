@@ -0,0 +1,192 @@
+package answerpdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu/model"
+)
+
+// Part is one respondent's answer PDF in a bundle.
+type Part struct {
+	// Title names the respondent in the outline, index and cover page.
+	Title string
+	// Details are shown under the title on the cover page; the first one
+	// also in the index.
+	Details []string
+	PDF     []byte
+}
+
+// BundleOptions select the pages added around the parts.
+type BundleOptions struct {
+	// Title heads the index and the cover pages.
+	Title string
+	// Index adds a page listing the parts with their first page.
+	Index bool
+	// Cover adds a page before each part.
+	Cover bool
+	// Font is as in Options.
+	Font string
+}
+
+// Bundle merges the parts, in order, into one PDF with an outline entry per
+// part and writes it to w.
+func Bundle(w io.Writer, parts []Part, opts BundleOptions) error {
+	if len(parts) == 0 {
+		return fmt.Errorf("no PDFs to bundle")
+	}
+	// pdfcpu would otherwise create a config directory in $HOME.
+	api.DisableConfigDir()
+	conf := model.NewDefaultConfiguration()
+
+	pages := make([]int, len(parts))
+	for i, p := range parts {
+		n, err := api.PageCount(bytes.NewReader(p.PDF), conf)
+		if err != nil {
+			return fmt.Errorf("%s: reading PDF: %w", p.Title, err)
+		}
+		pages[i] = n
+	}
+
+	// firstPages returns the page each part starts on (its cover, if any)
+	// after offset pages of index.
+	firstPages := func(offset int) []int {
+		first := make([]int, len(parts))
+		page := offset + 1
+		for i := range parts {
+			first[i] = page
+			page += pages[i]
+			if opts.Cover {
+				page++
+			}
+		}
+		return first
+	}
+	first := firstPages(0)
+	// The index lists the first pages, which depend on its own length.
+	var index []byte
+	for n := 1; opts.Index; {
+		first = firstPages(n)
+		var rendered int
+		var err error
+		if index, rendered, err = renderIndex(parts, first, opts); err != nil {
+			return err
+		}
+		if rendered == n {
+			break
+		}
+		n = rendered
+	}
+
+	var docs []io.ReadSeeker
+	var bookmarks []pdfcpu.Bookmark
+	if opts.Index {
+		docs = append(docs, bytes.NewReader(index))
+		bookmarks = append(bookmarks, pdfcpu.Bookmark{Title: "Index", PageFrom: 1, Bold: true})
+	}
+	for i, p := range parts {
+		if opts.Cover {
+			cover, err := renderCover(p, opts)
+			if err != nil {
+				return err
+			}
+			docs = append(docs, bytes.NewReader(cover))
+		}
+		docs = append(docs, bytes.NewReader(p.PDF))
+		bookmarks = append(bookmarks, pdfcpu.Bookmark{Title: p.Title, PageFrom: first[i]})
+	}
+
+	var merged bytes.Buffer
+	if err := api.MergeRaw(docs, &merged, false, conf); err != nil {
+		return fmt.Errorf("merging PDFs: %w", err)
+	}
+	if err := api.AddBookmarks(bytes.NewReader(merged.Bytes()), w, bookmarks, true, conf); err != nil {
+		return fmt.Errorf("adding bookmarks: %w", err)
+	}
+	return nil
+}
+
+// renderIndex returns the index of parts, whose first pages are first, and
+// its number of pages.
+func renderIndex(parts []Part, first []int, opts BundleOptions) ([]byte, int, error) {
+	pdf, err := newPDF(opts.Font)
+	if err != nil {
+		return nil, 0, err
+	}
+	pdf.SetTitle(opts.Title, true)
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.AddPage()
+	setFont(pdf, "B", 16, black)
+	pdf.MultiCell(0, 7.5, opts.Title, "", "L", false)
+	pdf.Ln(1)
+	setFont(pdf, "", 9, grey)
+	pdf.MultiCell(0, 4.5, fmt.Sprintf("%d respondents", len(parts)), "", "L", false)
+	pdf.Ln(4)
+
+	width := 210 - 2*margin
+	for i, p := range parts {
+		detail := ""
+		if len(p.Details) > 0 {
+			detail = p.Details[0]
+		}
+		setFont(pdf, "", 10, black)
+		pdf.CellFormat(10, lineHeight+1, strconv.Itoa(i+1), "", 0, "R", false, 0, "")
+		pdf.CellFormat(width*0.5, lineHeight+1, "  "+truncate(pdf.GetStringWidth, p.Title, width*0.5-2), "", 0, "L", false, 0, "")
+		setFont(pdf, "", 9, grey)
+		pdf.CellFormat(width*0.5-25, lineHeight+1, truncate(pdf.GetStringWidth, detail, width*0.5-27), "", 0, "L", false, 0, "")
+		setFont(pdf, "", 10, black)
+		pdf.CellFormat(15, lineHeight+1, strconv.Itoa(first[i]), "", 1, "R", false, 0, "")
+	}
+	var buf bytes.Buffer
+	n := pdf.PageNo()
+	if err := pdf.Output(&buf); err != nil {
+		return nil, 0, fmt.Errorf("rendering index: %w", err)
+	}
+	return buf.Bytes(), n, nil
+}
+
+// renderCover returns the cover page of p.
+func renderCover(p Part, opts BundleOptions) ([]byte, error) {
+	pdf, err := newPDF(opts.Font)
+	if err != nil {
+		return nil, err
+	}
+	pdf.SetMargins(margin, margin, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.AddPage()
+	if opts.Title != "" {
+		setFont(pdf, "", 10, grey)
+		pdf.MultiCell(0, lineHeight, opts.Title, "", "L", false)
+	}
+	pdf.SetY(100)
+	setFont(pdf, "B", 22, black)
+	pdf.MultiCell(0, 10, p.Title, "", "C", false)
+	pdf.Ln(4)
+	setFont(pdf, "", 11, grey)
+	for _, d := range p.Details {
+		pdf.MultiCell(0, lineHeight+1, d, "", "C", false)
+	}
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("rendering cover page: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// truncate shortens s with an ellipsis to fit width as measured by
+// stringWidth.
+func truncate(stringWidth func(string) float64, s string, width float64) string {
+	if stringWidth(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 && stringWidth(string(runes)+"…") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "…"
+}
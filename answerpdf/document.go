@@ -0,0 +1,107 @@
+// Package answerpdf renders an answer set as a PDF from the database, in
+// place of the EUSurvey PDF worker (/worker/createanswerpdf), and merges
+// answer PDFs into bookmarked bundles.
+package answerpdf
+
+import (
+	"eusurveymgr/analysis"
+	"eusurveymgr/db"
+	"strings"
+)
+
+// Item kinds.
+const (
+	Section  = "section"
+	Text     = "text"
+	Question = "question"
+)
+
+// Document is an answer set laid out for rendering.
+type Document struct {
+	SurveyID    int64
+	SurveyAlias string
+	SurveyTitle string
+	AnswerSetID int64
+	UniqueCode  string
+	Date        string
+	Items       []Item
+}
+
+// Item is a top-level survey element with the answers given to it. A
+// matrix has its answers in Rows, one per row question.
+type Item struct {
+	Kind    string
+	Title   string
+	Answers []string
+	Rows    []Item
+}
+
+// Load builds the document of set from its responses (see db.GetResponses)
+// and the element tree and option labels of its survey. Sections and text
+// blocks are kept as headings and paragraphs; every question is listed,
+// answered or not. Chosen options are shown by their label. Matrix rows
+// (see db.ListMatrixRows) are listed under their matrix.
+func Load(repo db.SurveyRepository, set *db.SubjectAnswerSetRow, responses []db.ResponseRow) (*Document, error) {
+	elements, err := repo.ListElements(set.SurveyID)
+	if err != nil {
+		return nil, err
+	}
+	options, err := repo.ListPossibleAnswers(set.SurveyID)
+	if err != nil {
+		return nil, err
+	}
+	matrixRows, err := repo.ListMatrixRows(set.SurveyID)
+	if err != nil {
+		return nil, err
+	}
+	labels := make(map[string]string)
+	for _, o := range options {
+		labels[o.UID] = analysis.PlainText(o.Title.String)
+	}
+
+	answers := make(map[string][]string)
+	for _, r := range responses {
+		value := r.Value.String
+		if r.PA_UID.String != "" {
+			if label, ok := labels[r.PA_UID.String]; ok {
+				value = label
+			} else if r.Question.Valid {
+				value = analysis.PlainText(r.Question.String)
+			}
+		}
+		if strings.TrimSpace(value) != "" {
+			answers[r.QuestionUID] = append(answers[r.QuestionUID], value)
+		}
+	}
+
+	doc := &Document{
+		SurveyID:    set.SurveyID,
+		SurveyAlias: set.Alias,
+		SurveyTitle: analysis.PlainText(set.Title),
+		AnswerSetID: set.AnswerSetID,
+		UniqueCode:  set.UniqueCode,
+		Date:        set.Date.String,
+	}
+	for _, e := range elements {
+		item := Item{Title: analysis.PlainText(e.Title.String)}
+		switch {
+		case strings.EqualFold(e.Type, "SECTION"):
+			item.Kind = Section
+		case strings.EqualFold(e.Type, "TEXT"):
+			item.Kind = Text
+		case analysis.KindOf(e.Type) != analysis.KindNone:
+			item.Kind = Question
+			item.Answers = answers[e.UID]
+			for _, row := range matrixRows {
+				if row.MatrixUID == e.UID {
+					item.Rows = append(item.Rows, Item{Kind: Question,
+						Title: analysis.PlainText(row.Title.String), Answers: answers[row.UID]})
+				}
+			}
+		default:
+			continue
+		}
+		doc.Items = append(doc.Items, item)
+	}
+	return doc, nil
+}
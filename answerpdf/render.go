@@ -0,0 +1,166 @@
+package answerpdf
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// Options is the page layout of the rendered PDF.
+type Options struct {
+	// Header is printed at the top of every page, right of Logo.
+	Header string
+	// Logo is a PNG or JPEG file, scaled to the header height.
+	Logo string
+	// Font is a TrueType file used instead of the built-in Go fonts, for
+	// regular and bold text. The Go fonts cover Romanian (ă â î ș ț, also
+	// the cedilla forms) and the rest of Latin, Greek and Cyrillic.
+	Font string
+}
+
+const (
+	family     = "body"
+	margin     = 20.0
+	logoHeight = 12.0
+	lineHeight = 5.5
+)
+
+var (
+	black = [3]int{0, 0, 0}
+	grey  = [3]int{110, 110, 110}
+)
+
+// Render writes doc as an A4 PDF to w.
+func Render(w io.Writer, doc *Document, opts Options) error {
+	pdf, err := newPDF(opts.Font)
+	if err != nil {
+		return err
+	}
+	if opts.Logo != "" {
+		if _, err := os.Stat(opts.Logo); err != nil {
+			return fmt.Errorf("PDF logo: %w", err)
+		}
+	}
+	pdf.SetTitle(doc.SurveyTitle, true)
+	pdf.SetSubject("Contribution "+doc.UniqueCode, true)
+	pdf.SetMargins(margin, margin+logoHeight, margin)
+	pdf.SetAutoPageBreak(true, margin)
+	pdf.AliasNbPages("")
+
+	pdf.SetHeaderFunc(func() {
+		x := margin
+		if opts.Logo != "" {
+			info := pdf.RegisterImageOptions(opts.Logo, fpdf.ImageOptions{ReadDpi: true})
+			pdf.ImageOptions(opts.Logo, x, margin/2, 0, logoHeight, false, fpdf.ImageOptions{}, 0, "")
+			if info != nil && info.Height() > 0 {
+				x += logoHeight*info.Width()/info.Height() + 4
+			}
+		}
+		if opts.Header != "" {
+			setFont(pdf, "", 10, grey)
+			pdf.SetXY(x, margin/2)
+			pdf.CellFormat(0, logoHeight, opts.Header, "", 0, "LM", false, 0, "")
+		}
+		if opts.Logo != "" || opts.Header != "" {
+			y := margin/2 + logoHeight + 2
+			pdf.SetDrawColor(grey[0], grey[1], grey[2])
+			pdf.Line(margin, y, 210-margin, y)
+		}
+		pdf.SetXY(margin, margin+logoHeight)
+	})
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-margin + 5)
+		setFont(pdf, "", 8, grey)
+		pdf.CellFormat(0, 5, doc.SurveyAlias+" · "+doc.UniqueCode, "", 0, "L", false, 0, "")
+		pdf.SetX(margin)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%d/{nb}", pdf.PageNo()), "", 0, "R", false, 0, "")
+	})
+
+	pdf.AddPage()
+	setFont(pdf, "B", 16, black)
+	pdf.MultiCell(0, 7.5, doc.SurveyTitle, "", "L", false)
+	pdf.Ln(1)
+	setFont(pdf, "", 9, grey)
+	pdf.MultiCell(0, 4.5, fmt.Sprintf("Contribution %s\nAnswer set %d, submitted %s",
+		doc.UniqueCode, doc.AnswerSetID, doc.Date), "", "L", false)
+	pdf.Ln(4)
+
+	for _, item := range doc.Items {
+		switch item.Kind {
+		case Section:
+			pdf.Ln(3)
+			setFont(pdf, "B", 13, black)
+			pdf.MultiCell(0, 6.5, item.Title, "", "L", false)
+			pdf.Ln(1)
+		case Text:
+			setFont(pdf, "", 10, grey)
+			pdf.MultiCell(0, lineHeight, item.Title, "", "L", false)
+			pdf.Ln(2)
+		case Question:
+			// Keep a question title together with its first answer.
+			if pdf.GetY() > 297-margin-3*lineHeight {
+				pdf.AddPage()
+			}
+			setFont(pdf, "B", 11, black)
+			pdf.MultiCell(0, lineHeight, item.Title, "", "L", false)
+			if len(item.Rows) == 0 {
+				writeAnswers(pdf, item.Answers, margin+5)
+			}
+			for _, row := range item.Rows {
+				setFont(pdf, "", 10, grey)
+				pdf.SetLeftMargin(margin + 5)
+				pdf.SetX(margin + 5)
+				pdf.MultiCell(0, lineHeight, row.Title, "", "L", false)
+				writeAnswers(pdf, row.Answers, margin+10)
+			}
+			pdf.SetLeftMargin(margin)
+			pdf.Ln(3)
+		}
+	}
+	if err := pdf.Output(w); err != nil {
+		return fmt.Errorf("rendering PDF: %w", err)
+	}
+	return nil
+}
+
+// writeAnswers lists answers indented to left, or "—" if there are none.
+func writeAnswers(pdf *fpdf.Fpdf, answers []string, left float64) {
+	pdf.SetLeftMargin(left)
+	pdf.SetX(left)
+	if len(answers) == 0 {
+		setFont(pdf, "", 11, grey)
+		pdf.MultiCell(0, lineHeight, "—", "", "L", false)
+	}
+	setFont(pdf, "", 11, black)
+	for _, a := range answers {
+		pdf.MultiCell(0, lineHeight, a, "", "L", false)
+	}
+}
+
+// newPDF returns an A4 document with the body font family: the TrueType
+// file font, or the Go fonts.
+func newPDF(font string) (*fpdf.Fpdf, error) {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	if font != "" {
+		data, err := os.ReadFile(font)
+		if err != nil {
+			return nil, fmt.Errorf("reading PDF font: %w", err)
+		}
+		pdf.AddUTF8FontFromBytes(family, "", data)
+		pdf.AddUTF8FontFromBytes(family, "B", data)
+	} else {
+		pdf.AddUTF8FontFromBytes(family, "", goregular.TTF)
+		pdf.AddUTF8FontFromBytes(family, "B", gobold.TTF)
+	}
+	pdf.SetCreator("eusurveymgr", false)
+	return pdf, nil
+}
+
+func setFont(pdf *fpdf.Fpdf, style string, size float64, color [3]int) {
+	pdf.SetFont(family, style, size)
+	pdf.SetTextColor(color[0], color[1], color[2])
+}
@@ -0,0 +1,355 @@
+// Package api is a read-only JSON REST API over surveys, answer sets and
+// answer PDFs, backed by the db and client packages.
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"eusurveymgr/client"
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"eusurveymgr/pseudo"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Pagination defaults for list endpoints.
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+// Server serves the API. Every endpoint except /healthz requires one of
+// Tokens as a bearer token.
+type Server struct {
+	Repo       db.SurveyRepository
+	Client     *client.Client
+	Tokens     []string
+	PDFTimeout int
+	// Pseudonymizer, if set, replaces names and emails in all responses.
+	Pseudonymizer *pseudo.Pseudonymizer
+
+	mux *http.ServeMux
+	// pdfMu serialises PDF generation: the client's session is not safe for
+	// concurrent use, and EUSurvey generates PDFs in a small worker pool.
+	pdfMu sync.Mutex
+}
+
+// New builds the API server.
+func New(repo db.SurveyRepository, c *client.Client, tokens []string) *Server {
+	s := &Server{Repo: repo, Client: c, Tokens: tokens, PDFTimeout: 120, mux: http.NewServeMux()}
+	s.mux.HandleFunc("GET /healthz", s.handleHealth)
+	s.mux.Handle("GET /surveys", s.auth(s.handleSurveys))
+	s.mux.Handle("GET /surveys/{id}/answers", s.auth(s.handleAnswers))
+	s.mux.Handle("GET /answers/{code}/responses", s.auth(s.handleResponses))
+	s.mux.Handle("GET /answers/{code}/pdf", s.auth(s.handlePDF))
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.mux.ServeHTTP(rec, r)
+	log.Infof("API -- %s %s %d (%s)", r.Method, r.URL.RequestURI(), rec.status, time.Since(start).Round(time.Millisecond))
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Page is the envelope of list endpoints. Next is the path of the following
+// page, empty on the last page.
+type Page[T any] struct {
+	Items  []T    `json:"items"`
+	Total  int    `json:"total"`
+	Limit  int    `json:"limit"`
+	Offset int    `json:"offset"`
+	Next   string `json:"next,omitempty"`
+}
+
+type Survey struct {
+	ID         int64  `json:"id"`
+	UID        string `json:"uid"`
+	Alias      string `json:"alias"`
+	Title      string `json:"title"`
+	Published  bool   `json:"published"`
+	NumAnswers int    `json:"num_answers"`
+	Created    string `json:"created,omitempty"`
+}
+
+type AnswerSet struct {
+	AnswerSetID int64  `json:"answer_set_id"`
+	UniqueCode  string `json:"uniquecode"`
+	Date        string `json:"date,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Email       string `json:"email,omitempty"`
+}
+
+type Responses struct {
+	AnswerSetID int64      `json:"answer_set_id"`
+	SurveyID    int64      `json:"survey_id"`
+	SurveyAlias string     `json:"survey_alias"`
+	UniqueCode  string     `json:"uniquecode"`
+	Date        string     `json:"date,omitempty"`
+	Responses   []Response `json:"responses"`
+}
+
+type Response struct {
+	PA_ID    int    `json:"pa_id"`
+	Question string `json:"question,omitempty"`
+	Value    string `json:"value"`
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "ok")
+}
+
+func (s *Server) handleSurveys(w http.ResponseWriter, r *http.Request) {
+	rows, err := s.Repo.ListSurveys()
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	surveys := make([]Survey, 0, len(rows))
+	for _, row := range rows {
+		surveys = append(surveys, Survey{
+			ID:         row.SurveyID,
+			UID:        row.SurveyUID,
+			Alias:      row.Alias,
+			Title:      row.Title,
+			Published:  row.Published,
+			NumAnswers: row.NumAnswers,
+			Created:    row.Created.String,
+		})
+	}
+	writePage(w, r, surveys)
+}
+
+func (s *Server) handleAnswers(w http.ResponseWriter, r *http.Request) {
+	surveyID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "survey id must be a number")
+		return
+	}
+	rows, err := s.Repo.ListAnswerSets(surveyID)
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	answers := make([]AnswerSet, 0, len(rows))
+	for _, row := range rows {
+		answers = append(answers, AnswerSet{
+			AnswerSetID: row.AnswerSetID,
+			UniqueCode:  row.UniqueCode,
+			Date:        row.Date.String,
+			Name:        s.Pseudonymizer.NullString(row.Name).String,
+			Email:       s.Pseudonymizer.NullString(row.Email).String,
+		})
+	}
+	writePage(w, r, answers)
+}
+
+func (s *Server) handleResponses(w http.ResponseWriter, r *http.Request) {
+	set, ok := s.answerSet(w, r)
+	if !ok {
+		return
+	}
+	rows, err := s.Repo.GetResponses(set.AnswerSetID)
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+
+	// Identity values are the first and last PA_ID=0 rows (name, email).
+	identity := make(map[string]bool)
+	if s.Pseudonymizer != nil {
+		var values []string
+		for _, row := range rows {
+			if row.PA_ID == 0 && row.Value.Valid {
+				values = append(values, row.Value.String)
+			}
+		}
+		if len(values) > 0 {
+			identity[values[0]] = true
+			identity[values[len(values)-1]] = true
+		}
+	}
+
+	resp := Responses{
+		AnswerSetID: set.AnswerSetID,
+		SurveyID:    set.SurveyID,
+		SurveyAlias: set.Alias,
+		UniqueCode:  set.UniqueCode,
+		Date:        set.Date.String,
+		Responses:   make([]Response, 0, len(rows)),
+	}
+	for _, row := range rows {
+		value := row.Value.String
+		if row.PA_ID == 0 && identity[value] {
+			value = s.Pseudonymizer.Apply(value)
+		}
+		resp.Responses = append(resp.Responses, Response{PA_ID: row.PA_ID, Question: row.Question.String, Value: value})
+	}
+	writeJSON(w, r, resp)
+}
+
+func (s *Server) handlePDF(w http.ResponseWriter, r *http.Request) {
+	set, ok := s.answerSet(w, r)
+	if !ok {
+		return
+	}
+	if s.Client == nil {
+		writeError(w, http.StatusServiceUnavailable, "PDF downloads are not available")
+		return
+	}
+	// EUSurvey renders the PDF with the respondent's identity in it.
+	if s.Pseudonymizer != nil {
+		writeError(w, http.StatusForbidden, "PDF downloads are not available with pseudonymisation")
+		return
+	}
+	s.pdfMu.Lock()
+	data, err := s.Client.GetAnswerPDF(set.UniqueCode, s.PDFTimeout)
+	s.pdfMu.Unlock()
+	if err != nil {
+		log.Errorf("API -- PDF for %s: %v", set.UniqueCode, err)
+		writeError(w, http.StatusBadGateway, "PDF generation failed")
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.pdf"`, set.UniqueCode))
+	writeBody(w, r, "application/pdf", data)
+}
+
+// answerSet resolves the {code} path value, writing a 404 if it is unknown.
+// PDFs are only requested for codes that exist in the database.
+func (s *Server) answerSet(w http.ResponseWriter, r *http.Request) (*db.SubjectAnswerSetRow, bool) {
+	set, err := s.Repo.GetAnswerSetByCode(r.PathValue("code"))
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, "answer set not found")
+		return nil, false
+	}
+	if err != nil {
+		s.serverError(w, err)
+		return nil, false
+	}
+	return set, true
+}
+
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || !s.validToken(token) {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="eusurveymgr"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		next(w, r)
+	})
+}
+
+func (s *Server) validToken(token string) bool {
+	valid := false
+	for _, t := range s.Tokens {
+		if t != "" && subtle.ConstantTimeCompare([]byte(token), []byte(t)) == 1 {
+			valid = true
+		}
+	}
+	return valid
+}
+
+func (s *Server) serverError(w http.ResponseWriter, err error) {
+	log.Errorf("API -- %v", err)
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
+
+// writePage writes one page of items, selected by the limit and offset query
+// parameters.
+func writePage[T any](w http.ResponseWriter, r *http.Request, items []T) {
+	q := r.URL.Query()
+	limit, offset := DefaultLimit, 0
+	var err error
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 || limit > MaxLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", MaxLimit))
+			return
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative number")
+			return
+		}
+	}
+
+	page := Page[T]{Items: []T{}, Total: len(items), Limit: limit, Offset: offset}
+	if offset < len(items) {
+		page.Items = items[offset:min(offset+limit, len(items))]
+	}
+	if offset+limit < len(items) {
+		next := url.Values{"limit": {strconv.Itoa(limit)}, "offset": {strconv.Itoa(offset + limit)}}
+		page.Next = r.URL.Path + "?" + next.Encode()
+	}
+	writeJSON(w, r, page)
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, v any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeBody(w, r, "application/json", buf.Bytes())
+}
+
+// writeBody writes a response with an ETag derived from its content, and
+// answers 304 Not Modified when the client already has it.
+func writeBody(w http.ResponseWriter, r *http.Request, contentType string, body []byte) {
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.Write(body)
+}
+
+func matchesETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(map[string]string{"error": message})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(buf.Bytes())
+}
@@ -4,8 +4,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 )
 
+// StatusError is returned by the WebService API calls for a non-2xx answer.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.Code, strings.TrimSpace(e.Body))
+}
+
 func (c *Client) doBasicGet(path string) ([]byte, error) {
 	body, status, err := c.doBasicGetStatus(path)
 	if err != nil {
@@ -22,6 +33,11 @@ func (c *Client) doBasicGetStatus(path string) ([]byte, int, error) {
 		return nil, 0, fmt.Errorf("creating request: %w", err)
 	}
 	req.SetBasicAuth(c.Username, c.Password)
+	if c.Budget != nil {
+		if err := c.Budget.Take(1); err != nil {
+			return nil, 0, err
+		}
+	}
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -34,7 +50,7 @@ func (c *Client) doBasicGetStatus(path string) ([]byte, int, error) {
 		return nil, resp.StatusCode, fmt.Errorf("reading response: %w", err)
 	}
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return body, resp.StatusCode, fmt.Errorf("HTTP %d: %s", resp.StatusCode, string(body))
+		return body, resp.StatusCode, &StatusError{Code: resp.StatusCode, Body: string(body)}
 	}
 	return body, resp.StatusCode, nil
 }
\ No newline at end of file
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"eusurveymgr/lockfile"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// BudgetFile is the name of the request counter inside the state dir.
+const BudgetFile = "api-budget.json"
+
+// ErrBudgetExhausted is returned instead of making a WebService request once
+// the day's budget is used up.
+var ErrBudgetExhausted = errors.New("daily WebService request budget exhausted (api_daily_budget)")
+
+// Budget limits the WebService requests made per calendar day (local time).
+// EUSurvey refuses requests above webservice.maxrequestsperday with HTTP 429
+// for the rest of the day; a budget below that limit keeps bulk commands
+// from locking out everyone else. The count is kept in a file in the state
+// dir so that it spans runs; a lock file next to it (see lockfile) serialises
+// the runs (cron, serve, an interactive command) that share the state dir.
+type Budget struct {
+	Limit int
+	path  string
+	mu    sync.Mutex
+}
+
+type budgetState struct {
+	Day  string `json:"day"`
+	Used int    `json:"used"`
+}
+
+// NewBudget returns a budget of limit requests per day counted in dir.
+func NewBudget(dir string, limit int) *Budget {
+	return &Budget{Limit: limit, path: filepath.Join(dir, BudgetFile)}
+}
+
+// Remaining returns how many requests are left today.
+func (b *Budget) Remaining() (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	unlock, err := lockfile.Lock(b.path + ".lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	s, err := b.load()
+	if err != nil {
+		return 0, err
+	}
+	return max(b.Limit-s.Used, 0), nil
+}
+
+// Take records n requests, or returns ErrBudgetExhausted without recording
+// anything if they do not fit into today's budget.
+func (b *Budget) Take(n int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	unlock, err := lockfile.Lock(b.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	s, err := b.load()
+	if err != nil {
+		return err
+	}
+	if s.Used+n > b.Limit {
+		return ErrBudgetExhausted
+	}
+	s.Used += n
+	return b.save(s)
+}
+
+func (b *Budget) load() (budgetState, error) {
+	today := time.Now().Format(time.DateOnly)
+	var s budgetState
+	content, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return budgetState{Day: today}, nil
+	}
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(content, &s); err != nil {
+		return s, fmt.Errorf("parsing %s: %w", BudgetFile, err)
+	}
+	if s.Day != today {
+		s = budgetState{Day: today}
+	}
+	return s, nil
+}
+
+// save writes the counter atomically (temporary file + rename).
+func (b *Budget) save(s budgetState) error {
+	content, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(b.path), BudgetFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), b.path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -3,8 +3,10 @@ package client
 import (
 	"crypto/tls"
 	"eusurveymgr/config"
+	"eusurveymgr/log"
 	"net/http"
 	"net/http/cookiejar"
+	"sync"
 	"time"
 )
 
@@ -13,6 +15,16 @@ type Client struct {
 	Username    string
 	Password    string
 	HTTPClient  *http.Client
+	// Logger receives the client's log lines. New sets it to log.Default();
+	// replace it to route or annotate them.
+	Logger      *log.Logger
+	// Budget, if set, counts the WebService (Basic auth) requests and
+	// refuses them once the day's budget is used up.
+	Budget      *Budget
+
+	// mu guards loggedIn; the client is shared by the serve, api and ui
+	// goroutines.
+	mu          sync.Mutex
 	loggedIn    bool
 }
 
@@ -22,7 +34,7 @@ func New(cfg *config.Configuration) *Client {
 	if cfg.InsecureTLS {
 		transport.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	}
-	return &Client{
+	c := &Client{
 		BaseURL:  cfg.BaseURL,
 		Username: cfg.WebUser,
 		Password: cfg.WebPassword,
@@ -34,5 +46,11 @@ func New(cfg *config.Configuration) *Client {
 				return http.ErrUseLastResponse
 			},
 		},
+		Logger: log.Default(),
+	}
+	c.HTTPClient.Transport = newInstrumentedTransport(c, transport)
+	if cfg.APIDailyBudget > 0 {
+		c.Budget = NewBudget(cfg.StateDir, cfg.APIDailyBudget)
 	}
+	return c
 }
\ No newline at end of file
@@ -0,0 +1,82 @@
+package client
+
+import (
+	"bytes"
+	"eusurveymgr/config"
+	"eusurveymgr/mockserver"
+	"strings"
+	"testing"
+	"time"
+)
+
+const demoCode = "ae8d5fec-daaf-4aba-b860-544d1f717d8a"
+
+// newTestClient returns a client of a mock server with opts, not logged in
+// yet.
+func newTestClient(t *testing.T, opts mockserver.Options) *Client {
+	t.Helper()
+	srv := mockserver.NewTestServer(mockserver.DemoFixture(), opts)
+	t.Cleanup(srv.Close)
+	return New(&config.Configuration{
+		BaseURL:        srv.URL,
+		WebUser:        "root",
+		WebPassword:    "secret",
+		TimeoutSeconds: 10,
+	})
+}
+
+func TestLoginRejected(t *testing.T) {
+	c := newTestClient(t, mockserver.Options{})
+	c.Password = "wrong"
+	if err := c.Login(); err == nil || !strings.Contains(err.Error(), "credentials rejected") {
+		t.Fatalf("got %v, want rejected credentials", err)
+	}
+}
+
+func TestGetAnswerPDF(t *testing.T) {
+	c := newTestClient(t, mockserver.Options{PDFDelay: 200 * time.Millisecond})
+	data, err := c.GetAnswerPDF(demoCode, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		t.Errorf("not a PDF: %.20q", data)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	c := newTestClient(t, mockserver.Options{SessionTTL: 100 * time.Millisecond})
+	if _, err := c.GetAnswerPDF(demoCode, 5); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	ready, err := c.IsAnswerPDFReady(demoCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ready {
+		t.Error("PDF not ready after the session expired: no new login")
+	}
+	html, err := c.GetAnswerHTML(demoCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(html, []byte("data:image/png;base64,")) {
+		t.Error("logo not inlined")
+	}
+}
+
+func TestGetResults(t *testing.T) {
+	c := newTestClient(t, mockserver.Options{ExportDelay: 100 * time.Millisecond})
+	task, err := c.PrepareResults("4609", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := c.GetResults(task, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(demoCode)) {
+		t.Errorf("export lacks answer set %s:\n%s", demoCode, data)
+	}
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"encoding/base64"
+	"eusurveymgr/log"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	styleBlockRe = regexp.MustCompile(`(?is)(<style\b[^>]*>)(.*?)(</style>)`)
+	styleAttrRe  = regexp.MustCompile(`(?is)(\sstyle\s*=\s*)(?:"([^"]*)"|'([^']*)')`)
+	stylesheetRe = regexp.MustCompile(`(?is)<link\b[^>]*\srel\s*=\s*["']?stylesheet["']?[^>]*>`)
+	imageRe      = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+	scriptRe     = regexp.MustCompile(`(?is)<script\b([^>]*)>\s*</script>`)
+	cssURLRe     = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+	cssImportRe  = regexp.MustCompile(`(?i)@import\s+(?:url\(\s*)?['"]([^'"]+)['"]\s*\)?[^;]*;`)
+)
+
+// attrRes match an attribute (preceded by white space, so src does not match
+// data-src) and capture its double-quoted, single-quoted or bare value.
+var attrRes = map[string]*regexp.Regexp{
+	"href": regexp.MustCompile(`(?is)\shref\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`),
+	"src":  regexp.MustCompile(`(?is)\ssrc\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`),
+}
+
+// GetAnswerHTML returns the contribution of uniqueCode as rendered by
+// /preparecontribution, as a self-contained page: stylesheets and scripts
+// are inlined and images, also those referenced from CSS, become data:
+// URIs. Only resources on the EUSurvey host are fetched, with the session;
+// others, and those that cannot be fetched (with a warning), keep pointing
+// at their absolute URL. Unlike GetAnswerPDF this does not depend on the
+// server's PDF pipeline.
+func (c *Client) GetAnswerHTML(uniqueCode string) ([]byte, error) {
+	logger := c.Logger.With("code", uniqueCode)
+	origin, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing base_url: %w", err)
+	}
+
+	page, _, base, err := c.fetch(c.BaseURL + "/preparecontribution/" + uniqueCode)
+	if err != nil {
+		return nil, fmt.Errorf("preparecontribution: %w", err)
+	}
+
+	in := &inliner{client: c, logger: logger, origin: origin, cache: make(map[string]string)}
+	doc := in.document(string(page), base)
+	logger.Infof("Answer HTML fetched (%d resources inlined, %d failed, %d external)", in.inlined, in.failed, in.external)
+	return []byte(doc), nil
+}
+
+// fetch GETs rawURL with the session, logging in again if it has expired,
+// and returns the body, its content type and the URL it was served from.
+func (c *Client) fetch(rawURL string) ([]byte, string, *url.URL, error) {
+	resp, err := c.sessionGet(rawURL)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return body, contentType, resp.Request.URL, nil
+}
+
+// inliner embeds the resources of a page, fetching each URL once. URLs
+// outside origin are never fetched: the requests carry the session cookie.
+type inliner struct {
+	client   *Client
+	logger   *log.Logger
+	origin   *url.URL
+	cache    map[string]string
+	inlined  int
+	failed   int
+	external int
+}
+
+// document rewrites page, served from base. Only CSS contexts (style
+// elements and attributes, linked stylesheets) are searched for url(), so
+// inlined scripts are left alone.
+func (in *inliner) document(page string, base *url.URL) string {
+	page = styleBlockRe.ReplaceAllStringFunc(page, func(block string) string {
+		m := styleBlockRe.FindStringSubmatch(block)
+		return m[1] + in.stylesheet(m[2], base, 0) + m[3]
+	})
+	page = styleAttrRe.ReplaceAllStringFunc(page, func(a string) string {
+		m := styleAttrRe.FindStringSubmatch(a)
+		css := in.stylesheet(html.UnescapeString(m[2]+m[3]), base, 0)
+		return m[1] + `"` + html.EscapeString(css) + `"`
+	})
+	page = stylesheetRe.ReplaceAllStringFunc(page, func(tag string) string {
+		ref, ok := attr(tag, "href")
+		if !ok {
+			return tag
+		}
+		u, css, ok := in.get(base, ref)
+		if !ok {
+			return setAttr(tag, "href", u.String())
+		}
+		return "<style>\n" + in.stylesheet(css, u, 0) + "\n</style>"
+	})
+	page = scriptRe.ReplaceAllStringFunc(page, func(tag string) string {
+		ref, ok := attr(tag, "src")
+		if !ok {
+			return tag
+		}
+		u, js, ok := in.get(base, ref)
+		if !ok {
+			return setAttr(tag, "src", u.String())
+		}
+		attrs := attrRes["src"].ReplaceAllLiteralString(scriptRe.FindStringSubmatch(tag)[1], "")
+		// A literal "</script" in the source would end the element early.
+		js = strings.ReplaceAll(js, "</script", `<\/script`)
+		return "<script" + attrs + ">\n" + js + "\n</script>"
+	})
+	return imageRe.ReplaceAllStringFunc(page, func(tag string) string {
+		ref, ok := attr(tag, "src")
+		if !ok {
+			return tag
+		}
+		return setAttr(tag, "src", in.dataURI(base, ref))
+	})
+}
+
+// stylesheet inlines the @imports of css and turns its url() references,
+// relative to base, into data: URIs.
+func (in *inliner) stylesheet(css string, base *url.URL, depth int) string {
+	css = cssImportRe.ReplaceAllStringFunc(css, func(rule string) string {
+		ref := cssImportRe.FindStringSubmatch(rule)[1]
+		if depth >= 4 {
+			return rule
+		}
+		u, imported, ok := in.get(base, ref)
+		if !ok {
+			return `@import url("` + u.String() + `");`
+		}
+		return in.stylesheet(imported, u, depth+1)
+	})
+	return cssURLRe.ReplaceAllStringFunc(css, func(ref string) string {
+		target := strings.TrimSpace(cssURLRe.FindStringSubmatch(ref)[1])
+		if strings.HasPrefix(target, "#") {
+			return ref
+		}
+		return `url("` + in.dataURI(base, target) + `")`
+	})
+}
+
+// get fetches ref, relative to base, as text. The returned URL is absolute
+// even if the fetch fails.
+func (in *inliner) get(base *url.URL, ref string) (*url.URL, string, bool) {
+	u, err := base.Parse(ref)
+	if err != nil {
+		in.warn(ref, err)
+		return base, "", false
+	}
+	if !in.local(u) {
+		return u, "", false
+	}
+	body, _, _, err := in.client.fetch(u.String())
+	if err != nil {
+		in.warn(u.String(), err)
+		return u, "", false
+	}
+	in.inlined++
+	return u, string(body), true
+}
+
+// dataURI returns ref, relative to base, as a data: URI, or as an absolute
+// URL if it cannot be fetched.
+func (in *inliner) dataURI(base *url.URL, ref string) string {
+	if strings.HasPrefix(ref, "data:") {
+		return ref
+	}
+	u, err := base.Parse(ref)
+	if err != nil {
+		in.warn(ref, err)
+		return ref
+	}
+	key := u.String()
+	if uri, ok := in.cache[key]; ok {
+		return uri
+	}
+	if !in.local(u) {
+		return key
+	}
+	uri := key
+	if body, contentType, _, err := in.client.fetch(key); err != nil {
+		in.warn(key, err)
+	} else {
+		in.inlined++
+		uri = "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(body)
+	}
+	in.cache[key] = uri
+	return uri
+}
+
+// local reports whether u is on the EUSurvey host, and counts it as
+// external if not.
+func (in *inliner) local(u *url.URL) bool {
+	if u.Scheme == in.origin.Scheme && u.Host == in.origin.Host {
+		return true
+	}
+	in.external++
+	in.logger.Debugf("Resource %s not inlined: not on %s", u, in.origin.Host)
+	return false
+}
+
+func (in *inliner) warn(ref string, err error) {
+	in.failed++
+	in.logger.Warnf("Resource %s not inlined: %v", ref, err)
+}
+
+// attr returns the unescaped value of attribute name in tag.
+func attr(tag, name string) (string, bool) {
+	m := attrRes[name].FindStringSubmatch(tag)
+	if m == nil {
+		return "", false
+	}
+	return html.UnescapeString(m[1] + m[2] + m[3]), true
+}
+
+// setAttr replaces the value of attribute name in tag.
+func setAttr(tag, name, value string) string {
+	return attrRes[name].ReplaceAllStringFunc(tag, func(a string) string {
+		return a[:1] + name + `="` + html.EscapeString(value) + `"`
+	})
+}
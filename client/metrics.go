@@ -0,0 +1,57 @@
+package client
+
+import (
+	"eusurveymgr/metrics"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// instrumentedTransport records every request to the EUSurvey server in the
+// HTTP metrics and the client's debug log.
+type instrumentedTransport struct {
+	base     http.RoundTripper
+	client   *Client
+	basePath string
+}
+
+func newInstrumentedTransport(c *Client, base http.RoundTripper) *instrumentedTransport {
+	t := &instrumentedTransport{base: base, client: c}
+	if u, err := url.Parse(c.BaseURL); err == nil {
+		t.basePath = strings.TrimSuffix(u.Path, "/")
+	}
+	return t
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	endpoint := endpointName(strings.TrimPrefix(req.URL.Path, t.basePath))
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+	duration := time.Since(start)
+	metrics.HTTPRequests.Inc(endpoint, status)
+	metrics.HTTPDuration.Observe(duration.Seconds(), endpoint)
+	t.client.Logger.With("method", req.Method, "endpoint", endpoint, "status", status,
+		"duration", duration.Round(time.Millisecond)).Debugf("EUSurvey request")
+	return resp, err
+}
+
+// endpointName reduces a request path to its first two segments, dropping
+// aliases, task IDs and UNIQUECODEs (/pdf/answer/<code> -> pdf/answer).
+func endpointName(path string) string {
+	var segments []string
+	for _, s := range strings.Split(path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	if len(segments) > 2 {
+		segments = segments[:2]
+	}
+	return strings.Join(segments, "/")
+}
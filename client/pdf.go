@@ -1,10 +1,12 @@
 package client
 
 import (
-	"eusurveymgr/log"
+	"eusurveymgr/metrics"
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 )
 
 // GetSurveyPDF downloads the survey form PDF via Basic Auth.
@@ -16,39 +18,38 @@ func (c *Client) GetSurveyPDF(alias string) ([]byte, error) {
 	return data, nil
 }
 
-// CreateAnswerPDF triggers server-side PDF generation for an answer.
-// Requires prior Login().
+// CreateAnswerPDF triggers server-side PDF generation for an answer,
+// logging in as needed. Anything but "OK" may come from an expired session,
+// so it is retried once with a new one.
 func (c *Client) CreateAnswerPDF(uniqueCode string) error {
-	if err := c.Login(); err != nil {
-		return err
-	}
-
 	url := c.BaseURL + "/worker/createanswerpdf/" + uniqueCode
-	resp, err := c.HTTPClient.Do(mustNewRequest("GET", url))
-	if err != nil {
-		return fmt.Errorf("createanswerpdf: %w", err)
-	}
-	body, _ := io.ReadAll(resp.Body)
-	resp.Body.Close()
+	var result string
+	for retried := false; ; retried = true {
+		resp, err := c.sessionGet(url)
+		if err != nil {
+			return fmt.Errorf("createanswerpdf: %w", err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
 
-	result := string(body)
+		result = string(body)
+		if result == "OK" || retried {
+			break
+		}
+		c.Logger.With("code", uniqueCode).Infof("createanswerpdf returned %q, logging in again", result)
+		c.expire()
+	}
 	if result != "OK" {
 		return fmt.Errorf("createanswerpdf returned %q (expected OK)", result)
 	}
-	log.Infof("PDF generation triggered for %s", uniqueCode)
+	c.Logger.With("code", uniqueCode).Infof("PDF generation triggered")
 	return nil
 }
 
 // DownloadAnswerPDF downloads a previously generated answer PDF.
-// Requires prior Login().
 func (c *Client) DownloadAnswerPDF(uniqueCode string) ([]byte, error) {
-	if err := c.Login(); err != nil {
-		return nil, err
-	}
-
 	url := c.BaseURL + "/pdf/answer/" + uniqueCode
-	req, _ := http.NewRequest("GET", url, nil)
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.sessionGet(url)
 	if err != nil {
 		return nil, fmt.Errorf("download answer PDF: %w", err)
 	}
@@ -63,18 +64,69 @@ func (c *Client) DownloadAnswerPDF(uniqueCode string) ([]byte, error) {
 
 // IsAnswerPDFReady checks if a PDF has been generated.
 func (c *Client) IsAnswerPDFReady(uniqueCode string) (bool, error) {
-	if err := c.Login(); err != nil {
-		return false, err
-	}
-
 	url := c.BaseURL + "/pdf/answerready/" + uniqueCode
-	resp, err := c.HTTPClient.Do(mustNewRequest("GET", url))
+	resp, err := c.sessionGet(url)
 	if err != nil {
 		return false, fmt.Errorf("answerready: %w", err)
 	}
 	body, _ := io.ReadAll(resp.Body)
 	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("answerready: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
 
 	result := string(body)
 	return result == "exists" || result == "OK", nil
-}
\ No newline at end of file
+}
+
+// GetAnswerPDF returns the answer PDF for uniqueCode, triggering server-side
+// generation first if it does not exist yet and polling readiness until
+// timeoutSeconds have passed.
+func (c *Client) GetAnswerPDF(uniqueCode string, timeoutSeconds int) ([]byte, error) {
+	start := time.Now()
+	data, err := c.getAnswerPDF(uniqueCode, timeoutSeconds)
+	metrics.PDFGeneration.Since(start, metrics.Result(err))
+	return data, err
+}
+
+func (c *Client) getAnswerPDF(uniqueCode string, timeoutSeconds int) ([]byte, error) {
+	logger := c.Logger.With("code", uniqueCode)
+	// Check if PDF already exists before triggering generation
+	ready, err := c.IsAnswerPDFReady(uniqueCode)
+	if err != nil {
+		return nil, fmt.Errorf("checking PDF readiness: %w", err)
+	}
+
+	if !ready {
+		logger.Infof("Triggering PDF generation...")
+		if err := c.CreateAnswerPDF(uniqueCode); err != nil {
+			return nil, err
+		}
+
+		logger.Infof("Waiting for PDF to be ready...")
+		deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
+		delay := time.Second
+		for {
+			ready, err = c.IsAnswerPDFReady(uniqueCode)
+			if err != nil {
+				return nil, fmt.Errorf("checking PDF readiness: %w", err)
+			}
+			if ready {
+				break
+			}
+			if time.Now().After(deadline) {
+				return nil, fmt.Errorf("PDF generation timed out after %ds", timeoutSeconds)
+			}
+			logger.Debugf("PDF not ready yet, retrying in %v...", delay)
+			time.Sleep(delay)
+			if delay < 5*time.Second {
+				delay += time.Second
+			}
+		}
+	} else {
+		logger.Infof("PDF already exists")
+	}
+
+	logger.Infof("Downloading PDF...")
+	return c.DownloadAnswerPDF(uniqueCode)
+}
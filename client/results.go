@@ -1,8 +1,9 @@
 package client
 
 import (
+	"errors"
 	"fmt"
-	"eusurveymgr/log"
+	"eusurveymgr/metrics"
 	"strings"
 	"time"
 )
@@ -24,22 +25,34 @@ func (c *Client) PrepareResults(formID string, showIDs bool) (string, error) {
 }
 
 func (c *Client) GetResults(taskID string, timeoutSeconds int) ([]byte, error) {
+	start := time.Now()
+	data, err := c.pollResults(taskID, timeoutSeconds)
+	metrics.ExportPollDuration.Since(start, metrics.Result(err))
+	return data, err
+}
+
+func (c *Client) pollResults(taskID string, timeoutSeconds int) ([]byte, error) {
 	deadline := time.Now().Add(time.Duration(timeoutSeconds) * time.Second)
 	delay := 1 * time.Second
+	logger := c.Logger.With("task", taskID)
 
 	for {
+		metrics.ExportPollAttempts.Inc()
 		data, status, err := c.doBasicGetStatus("/webservice/getResults/" + taskID)
+		if errors.Is(err, ErrBudgetExhausted) {
+			return nil, err
+		}
 		if err != nil {
 			if time.Now().After(deadline) {
 				return nil, fmt.Errorf("getResults timed out after %ds: %w", timeoutSeconds, err)
 			}
-			log.Debugf("Results not ready yet, retrying in %v...", delay)
+			logger.Debugf("Results not ready yet, retrying in %v...", delay)
 		} else if status == 204 {
 			// 204 No Content = export still in progress
 			if time.Now().After(deadline) {
 				return nil, fmt.Errorf("getResults timed out after %ds (still 204)", timeoutSeconds)
 			}
-			log.Debugf("Export in progress (HTTP 204), retrying in %v...", delay)
+			logger.Debugf("Export in progress (HTTP 204), retrying in %v...", delay)
 		} else {
 			return data, nil
 		}
@@ -1,7 +1,7 @@
 package client
 
 import (
-	"eusurveymgr/log"
+	"eusurveymgr/metrics"
 	"fmt"
 	"io"
 	"net/http"
@@ -13,10 +13,57 @@ import (
 var csrfRe = regexp.MustCompile(`<meta\s+name="_csrf"\s+content="([^"]+)"`)
 
 func (c *Client) Login() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	if c.loggedIn {
 		return nil
 	}
+	err := c.login()
+	metrics.Logins.Inc(metrics.Result(err))
+	return err
+}
+
+// expire forgets the session, so that the next Login logs in again.
+func (c *Client) expire() {
+	c.mu.Lock()
+	c.loggedIn = false
+	c.mu.Unlock()
+}
+
+// sessionGet GETs rawURL with the session, logging in first. EUSurvey
+// answers a request of an expired session with a redirect to the login
+// page; sessionGet then logs in again once and retries.
+func (c *Client) sessionGet(rawURL string) (*http.Response, error) {
+	for retried := false; ; retried = true {
+		if err := c.Login(); err != nil {
+			return nil, err
+		}
+		resp, err := c.HTTPClient.Do(mustNewRequest("GET", rawURL))
+		if err != nil {
+			return nil, err
+		}
+		if !isLoginRedirect(resp) {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if retried {
+			return nil, fmt.Errorf("redirected to the login page after logging in again")
+		}
+		c.Logger.Infof("EUSurvey session expired, logging in again")
+		c.expire()
+	}
+}
 
+// isLoginRedirect reports whether resp redirects to the login page.
+func isLoginRedirect(resp *http.Response) bool {
+	if resp.StatusCode < 300 || resp.StatusCode >= 400 {
+		return false
+	}
+	loc, err := resp.Location()
+	return err == nil && strings.HasSuffix(loc.Path, "/auth/login")
+}
+
+func (c *Client) login() error {
 	// Step 1: GET /auth/login to obtain CSRF token
 	loginURL := c.BaseURL + "/auth/login"
 	resp, err := c.HTTPClient.Do(mustNewRequest("GET", loginURL))
@@ -31,7 +78,7 @@ func (c *Client) Login() error {
 		return fmt.Errorf("CSRF token not found in login page")
 	}
 	csrf := string(matches[1])
-	log.Debugf("CSRF token: %s", csrf)
+	c.Logger.Debugf("CSRF token: %s", csrf)
 
 	// Step 2: POST /login with credentials
 	form := url.Values{
@@ -52,9 +99,14 @@ func (c *Client) Login() error {
 	if resp.StatusCode != http.StatusFound && resp.StatusCode != http.StatusOK {
 		return fmt.Errorf("login failed: HTTP %d", resp.StatusCode)
 	}
+	// Spring Security also answers a rejected login with a 302, back to
+	// the login page.
+	if loc := resp.Header.Get("Location"); strings.Contains(loc, "error") {
+		return fmt.Errorf("login failed: credentials rejected (redirected to %s)", loc)
+	}
 
 	c.loggedIn = true
-	log.Infof("Logged in to EUSurvey")
+	c.Logger.Infof("Logged in to EUSurvey")
 	return nil
 }
 
@@ -4,6 +4,9 @@ import (
 	"bytes"
 	"encoding/xml"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -20,7 +23,9 @@ type Survey struct {
 	Title string `xml:"Title" json:"title"`
 }
 
-// XML response for /webservice/getSurveyMetadata/{alias}
+// XML response for /webservice/getSurveyMetadata/{alias}. The typed fields
+// are the documented elements; Fields holds every element and attribute of
+// the response, including the ones EUSurvey adds without notice.
 type SurveyMetadata struct {
 	XMLName    xml.Name `xml:"Survey"`
 	ID         string   `xml:"id,attr" json:"id"`
@@ -35,6 +40,84 @@ type SurveyMetadata struct {
 	Results    int      `xml:"Results" json:"results"`
 	Security   string   `xml:"Security" json:"security"`
 	Visibility string   `xml:"Visibility" json:"visibility"`
+
+	Fields []MetadataField `xml:"-" json:"fields"`
+	// Raw is the sanitised XML as received.
+	Raw []byte `xml:"-" json:"-"`
+}
+
+// MetadataField is one element or attribute of the getSurveyMetadata XML.
+// Name is the element path below <Survey> joined with dots
+// ("Languages.Language"), with "[n]" from the second element of the same
+// name on and "@attr" for attributes; the root's attributes are just "id"
+// and "alias". Only elements without child elements have a value.
+type MetadataField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Field returns the value of the named field.
+func (m *SurveyMetadata) Field(name string) (string, bool) {
+	for _, f := range m.Fields {
+		if f.Name == name {
+			return f.Value, true
+		}
+	}
+	return "", false
+}
+
+// metadataFields flattens an XML document into fields in document order.
+func metadataFields(data []byte) ([]MetadataField, error) {
+	type frame struct {
+		path     string
+		text     strings.Builder
+		children map[string]int
+	}
+	var fields []MetadataField
+	var stack []*frame
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return fields, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			f := &frame{children: make(map[string]int)}
+			if n := len(stack); n > 0 {
+				parent := stack[n-1]
+				parent.children[t.Name.Local]++
+				f.path = t.Name.Local
+				if parent.path != "" {
+					f.path = parent.path + "." + f.path
+				}
+				if i := parent.children[t.Name.Local]; i > 1 {
+					f.path += "[" + strconv.Itoa(i) + "]"
+				}
+			}
+			for _, a := range t.Attr {
+				name := a.Name.Local
+				if f.path != "" {
+					name = f.path + "@" + name
+				}
+				fields = append(fields, MetadataField{Name: name, Value: a.Value})
+			}
+			stack = append(stack, f)
+		case xml.CharData:
+			if n := len(stack); n > 0 {
+				stack[n-1].text.Write(t)
+			}
+		case xml.EndElement:
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if f.path != "" && len(f.children) == 0 {
+				fields = append(fields, MetadataField{Name: f.path, Value: strings.TrimSpace(f.text.String())})
+			}
+		}
+	}
 }
 
 // sanitizeXML replaces invalid UTF-8 bytes with the Unicode replacement character.
@@ -74,9 +157,14 @@ func (c *Client) GetSurveyMetadata(alias string) (*SurveyMetadata, error) {
 	if err != nil {
 		return nil, fmt.Errorf("getSurveyMetadata: %w", err)
 	}
+	data = sanitizeXML(data)
 	var meta SurveyMetadata
-	if err := xml.Unmarshal(sanitizeXML(data), &meta); err != nil {
+	if err := xml.Unmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("parsing getSurveyMetadata XML: %w", err)
+	}
+	if meta.Fields, err = metadataFields(data); err != nil {
 		return nil, fmt.Errorf("parsing getSurveyMetadata XML: %w", err)
 	}
+	meta.Raw = data
 	return &meta, nil
 }
\ No newline at end of file
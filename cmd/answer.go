@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"eusurveymgr/pseudo"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+var answerCmd = &cobra.Command{
+	Use:   "answer",
+	Short: "Download rendered answers",
+	Long:  "Download individual contributions as rendered by the EUSurvey web application.",
+}
+
+// answerTarget is the answer set selected by --code, or by --email and
+// --survey.
+type answerTarget struct {
+	UniqueCode  string
+	AnswerSetID int64
+	Email       string
+}
+
+// addAnswerFlags registers the flags read by lookupAnswer and --output.
+func addAnswerFlags(cmd *cobra.Command) {
+	cmd.Flags().String("code", "", "Answer UNIQUECODE")
+	cmd.Flags().String("email", "", "Respondent email address")
+	cmd.Flags().Int64("survey", 0, "Survey ID (required with --email)")
+	cmd.Flags().String("output", ".", "Output directory")
+}
+
+// lookupAnswer returns the answer set selected on the command line, looking
+// the UNIQUECODE up in repo for --email. A nil repo is opened on demand.
+func lookupAnswer(cmd *cobra.Command, repo db.SurveyRepository) (answerTarget, error) {
+	code, _ := cmd.Flags().GetString("code")
+	email, _ := cmd.Flags().GetString("email")
+	surveyID, _ := cmd.Flags().GetInt64("survey")
+
+	if code != "" {
+		return answerTarget{UniqueCode: code}, nil
+	}
+	if email == "" {
+		return answerTarget{}, fmt.Errorf("provide either --code or --email (with --survey)")
+	}
+	if surveyID == 0 {
+		return answerTarget{}, fmt.Errorf("--survey is required when using --email")
+	}
+	if repo == nil {
+		r, err := openRepository()
+		if err != nil {
+			return answerTarget{}, fmt.Errorf("connecting to DB for UNIQUECODE lookup: %w", err)
+		}
+		defer r.Close()
+		repo = r
+	}
+
+	t := answerTarget{Email: email}
+	var err error
+	t.AnswerSetID, t.UniqueCode, err = repo.LookupUniqueCode(email, surveyID)
+	if err != nil {
+		return answerTarget{}, err
+	}
+	log.Infof("Found ANSWER_SET_ID=%d UNIQUECODE=%s", t.AnswerSetID, t.UniqueCode)
+	return t, nil
+}
+
+// save writes data to outDir as <ANSWER_SET_ID>--<email>.<ext> for an email
+// lookup (the email pseudonymised by p), else <UNIQUECODE>.<ext>.
+func (t answerTarget) save(outDir, ext string, p *pseudo.Pseudonymizer, data []byte) (string, error) {
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return "", fmt.Errorf("creating output directory: %w", err)
+	}
+	filename := t.UniqueCode + "." + ext
+	if t.Email != "" {
+		filename = fmt.Sprintf("%d--%s.%s", t.AnswerSetID, p.Apply(t.Email), ext)
+	}
+	outPath := filepath.Join(outDir, filename)
+	if err := os.WriteFile(outPath, data, 0644); err != nil {
+		return "", fmt.Errorf("writing %s: %w", ext, err)
+	}
+	return outPath, nil
+}
+
+var answerHTMLCmd = &cobra.Command{
+	Use:   "html",
+	Short: "Download the rendered answer as self-contained HTML",
+	Long: `Download a contribution as rendered by /preparecontribution/{uniquecode},
+the page EUSurvey itself builds answer PDFs from. Stylesheets, scripts and
+images are embedded, so the file opens offline. This works even when the
+server's PDF pipeline is broken. EUSurvey renders the page, so
+--pseudonymize is rejected.
+
+Provide either --code for a known UNIQUECODE, or --email and --survey
+to look up the code from the database.`,
+	Example: `  eusurveymgr answer html --code ae8d5fec-daaf-4aba-b860-544d1f717d8a
+  eusurveymgr answer html --email user@example.com --survey 4578 --output ./answers`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outDir, _ := cmd.Flags().GetString("output")
+		if err := rejectPseudonymize("answer html"); err != nil {
+			return err
+		}
+		c := newClient()
+
+		t, err := lookupAnswer(cmd, nil)
+		if err != nil {
+			return err
+		}
+		data, err := c.GetAnswerHTML(t.UniqueCode)
+		if err != nil {
+			return err
+		}
+		outPath, err := t.save(outDir, "html", nil, data)
+		if err != nil {
+			return err
+		}
+		log.With("code", t.UniqueCode).Infof("Answer HTML saved to %s (%d bytes)", outPath, len(data))
+		return nil
+	},
+}
+
+func init() {
+	addAnswerFlags(answerHTMLCmd)
+
+	answerCmd.AddCommand(answerHTMLCmd)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"eusurveymgr/api"
+	"eusurveymgr/log"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var apiCmd = &cobra.Command{
+	Use:   "api",
+	Short: "Serve a read-only JSON REST API",
+	Long: `Serve surveys, answer sets, responses and answer PDFs over HTTP:
+
+  GET /surveys                    surveys (latest version per UID)
+  GET /surveys/{id}/answers       answer sets of a survey
+  GET /answers/{code}/responses   all answers of one answer set (by UNIQUECODE)
+  GET /answers/{code}/pdf         answer PDF (generated through EUSurvey if needed)
+  GET /healthz                    liveness, no authentication
+
+Every endpoint except /healthz requires "Authorization: Bearer <token>" with
+one of the api_tokens from the config. List endpoints are paginated with
+?limit= (default 100, max 1000) and ?offset=, and return
+{"items", "total", "limit", "offset", "next"}. Responses carry an ETag;
+requests with a matching If-None-Match get 304 Not Modified.
+
+Data comes from MySQL (or --source); PDFs use the same session flow as
+'pdf answer' and are generated one at a time. With --pseudonymize, names and
+emails are pseudonymised in every response.`,
+	Example: `  eusurveymgr api
+  eusurveymgr api --listen 0.0.0.0:8080
+  curl -H "Authorization: Bearer $TOKEN" http://127.0.0.1:8080/surveys/4609/answers?limit=50`,
+	Annotations: map[string]string{"daemon": "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		if len(cfg.APITokens) == 0 {
+			return fmt.Errorf("api_tokens must be set in the config")
+		}
+		p, err := identityPseudonymizer()
+		if err != nil {
+			return err
+		}
+
+		repo, err := openRepository()
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+
+		s := api.New(repo, newClient(), cfg.APITokens)
+		s.PDFTimeout = cfg.TimeoutSeconds
+		s.Pseudonymizer = p
+		return listenAndServe(listen, s)
+	},
+}
+
+// listenAndServe serves h until SIGINT/SIGTERM, then lets in-flight requests
+// finish.
+func listenAndServe(addr string, h http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: h, ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	log.Infof("Listening on http://%s", addr)
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+	log.Infof("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func init() {
+	apiCmd.Flags().String("listen", "127.0.0.1:8080", "Listen address")
+}
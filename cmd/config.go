@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"eusurveymgr/config"
+	"eusurveymgr/output"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Create, check and inspect the configuration",
+	Long:  "Create, check and inspect the configuration file and its profiles.",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective configuration",
+	Long: `Print the configuration after applying the profile, defaults and environment
+overrides, with passwords, keys and tokens masked, one key per line. --format
+json or yaml prints the configuration as a file instead. --origin adds where
+each value came from (file, profile <name>, env <VARIABLE> or default) and the
+environment variable that overrides it.`,
+	Example: `  eusurveymgr config show
+  eusurveymgr config show --format yaml
+  eusurveymgr --profile prod config show --origin`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		origin, _ := cmd.Flags().GetBool("origin")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		if opts.Structured() && !origin {
+			return output.Encode(os.Stdout, opts.Format, cfg.Masked())
+		}
+		return output.Render(os.Stdout, opts, settingOriginColumns(origin), cfg.Settings())
+	},
+}
+
+// settingOriginColumns describes config.Setting rows; origin and env are
+// only shown by default with --origin.
+func settingOriginColumns(origin bool) []output.Column[config.Setting] {
+	return []output.Column[config.Setting]{
+		{Name: "key", Value: func(s config.Setting) any { return s.Key }},
+		{Name: "value", Width: 60, Value: func(s config.Setting) any { return s.Value }},
+		{Name: "origin", Hidden: !origin, Value: func(s config.Setting) any { return s.Origin }},
+		{Name: "env", Hidden: !origin, Value: func(s config.Setting) any { return s.Env }},
+	}
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file strictly",
+	Long: `Check the config file: unknown or misspelled keys are errors (they are only
+warned about when loading), and the values must make sense: http(s) base_url,
+valid db_port, positive timeout, known pseudonym_mode, complete hooks, ui_users
+and jobs with valid cron schedules. Every profile is checked with its
+inherited values. Environment overrides are applied, as when running.
+Secret sources are checked without reading them: *_file files must exist,
+*_command programs must be found and secrets_file must exist, but no command
+is run and the secrets file is not unlocked.`,
+	Example: `  eusurveymgr config validate
+  eusurveymgr --config /etc/eusurveymgr.json config validate`,
+	Annotations: map[string]string{"config": "none"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.ValidateFile(cfgFile); err != nil {
+			for _, line := range strings.Split(err.Error(), "\n") {
+				fmt.Fprintf(os.Stderr, "  %s\n", line)
+			}
+			return fmt.Errorf("%s is not valid", cfgFile)
+		}
+		fmt.Printf("%s is valid\n", cfgFile)
+		return nil
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Create a config file interactively",
+	Long: `Ask for the EUSurvey and database settings and write a config file (to
+--output, default the --config path) that passes 'config validate'. Press
+Enter to accept the value in brackets. Passwords may be left empty and given
+through EUSURVEYMGR_WEB_PASSWORD and EUSURVEYMGR_DB_PASSWORD instead; on a
+terminal they are not echoed while typing. The file is created with mode 0600.`,
+	Example: `  eusurveymgr config init
+  eusurveymgr config init --output /etc/eusurveymgr.json --force`,
+	Annotations: map[string]string{"config": "none"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		output, _ := cmd.Flags().GetString("output")
+		force, _ := cmd.Flags().GetBool("force")
+		if output == "" {
+			output = cfgFile
+		}
+		if _, err := os.Stat(output); err == nil && !force {
+			return fmt.Errorf("%s already exists (use --force to overwrite)", output)
+		} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+
+		in := bufio.NewReader(os.Stdin)
+		readLine := func(secret bool) (string, error) {
+			if secret && term.IsTerminal(int(os.Stdin.Fd())) {
+				value, err := term.ReadPassword(int(os.Stdin.Fd()))
+				fmt.Fprintln(os.Stderr)
+				return string(value), err
+			}
+			line, err := in.ReadString('\n')
+			if err == io.EOF && line != "" {
+				err = nil
+			}
+			return line, err
+		}
+		ask := func(label, def string, secret bool, check func(string) error) (string, error) {
+			for {
+				if def != "" {
+					fmt.Fprintf(os.Stderr, "%s [%s]: ", label, def)
+				} else {
+					fmt.Fprintf(os.Stderr, "%s: ", label)
+				}
+				line, err := readLine(secret)
+				if err != nil {
+					return "", fmt.Errorf("reading %s: %w", label, err)
+				}
+				value := strings.TrimSpace(line)
+				if value == "" {
+					value = def
+				}
+				if check == nil {
+					return value, nil
+				}
+				if err := check(value); err != nil {
+					fmt.Fprintf(os.Stderr, "  %v\n", err)
+					continue
+				}
+				return value, nil
+			}
+		}
+		required := func(s string) error {
+			if s == "" {
+				return fmt.Errorf("a value is required")
+			}
+			return nil
+		}
+		httpURL := func(s string) error {
+			if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("enter an http(s) URL, e.g. https://eusurvey.example.org/eusurvey")
+			}
+			return nil
+		}
+		number := func(min, max int) func(string) error {
+			return func(s string) error {
+				if n, err := strconv.Atoi(s); err != nil || n < min || n > max {
+					return fmt.Errorf("enter a number from %d to %d", min, max)
+				}
+				return nil
+			}
+		}
+		yesNo := func(s string) error {
+			if s != "y" && s != "n" {
+				return fmt.Errorf("enter y or n")
+			}
+			return nil
+		}
+
+		var c config.Configuration
+		var port, timeout, insecure string
+		steps := []struct {
+			label, def string
+			dst        *string
+			secret     bool
+			check      func(string) error
+		}{
+			{"EUSurvey base URL", "", &c.BaseURL, false, httpURL},
+			{"Web user", "", &c.WebUser, false, required},
+			{"Web password (empty: use EUSURVEYMGR_WEB_PASSWORD)", "", &c.WebPassword, true, nil},
+			{"Database host", "127.0.0.1", &c.DBHost, false, required},
+			{"Database port", "3306", &port, false, number(1, 65535)},
+			{"Database name", "eusurveydb", &c.DBName, false, required},
+			{"Database user", "", &c.DBUser, false, nil},
+			{"Database password (empty: use EUSURVEYMGR_DB_PASSWORD)", "", &c.DBPassword, true, nil},
+			{"Output directory", ".", &c.OutputDir, false, required},
+			{"Timeout in seconds", "30", &timeout, false, number(1, 86400)},
+			{"Skip TLS certificate verification (y/n)", "n", &insecure, false, yesNo},
+		}
+		for _, s := range steps {
+			value, err := ask(s.label, s.def, s.secret, s.check)
+			if err != nil {
+				return err
+			}
+			*s.dst = value
+		}
+		c.DBPort, _ = strconv.Atoi(port)
+		c.TimeoutSeconds, _ = strconv.Atoi(timeout)
+		c.InsecureTLS = insecure == "y"
+		c.StateDir = c.OutputDir
+		if err := c.Validate(); err != nil {
+			return err
+		}
+
+		// Only the keys asked for; everything else keeps its default.
+		file := struct {
+			BaseURL        string `json:"base_url"`
+			WebUser        string `json:"web_user"`
+			WebPassword    string `json:"web_password,omitempty"`
+			DBHost         string `json:"db_host"`
+			DBPort         int    `json:"db_port"`
+			DBName         string `json:"db_name"`
+			DBUser         string `json:"db_user,omitempty"`
+			DBPassword     string `json:"db_password,omitempty"`
+			OutputDir      string `json:"output_dir"`
+			TimeoutSeconds int    `json:"timeout_seconds"`
+			InsecureTLS    bool   `json:"insecure_tls"`
+		}{c.BaseURL, c.WebUser, c.WebPassword, c.DBHost, c.DBPort, c.DBName, c.DBUser,
+			c.DBPassword, c.OutputDir, c.TimeoutSeconds, c.InsecureTLS}
+		data, err := json.MarshalIndent(file, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(output, append(data, '\n'), 0600); err != nil {
+			return fmt.Errorf("writing config: %w", err)
+		}
+		fmt.Printf("Wrote %s\n", output)
+		return nil
+	},
+}
+
+var configProfilesCmd = &cobra.Command{
+	Use:   "profiles",
+	Short: "List the profiles of the config file",
+	Long: `List the profiles defined under "profiles" in the config file, each with the
+values it resolves to (top-level values are the defaults of every profile):
+
+  "base_url": "https://eusurvey.example.org/eusurvey",
+  "default_profile": "test",
+  "profiles": {
+    "test": {"base_url": "https://eusurvey-test.example.org/eusurvey", "db_host": "test-db"},
+    "prod": {"db_host": "prod-db", "output_dir": "/srv/eusurvey"}
+  }
+
+The active profile, selected by --profile, EUSURVEYMGR_PROFILE or
+default_profile (in that order), has active=true. Secrets are not resolved:
+no *_command is run and the secrets file is not unlocked.`,
+	Example: `  eusurveymgr config profiles
+  eusurveymgr --profile prod config profiles --json`,
+	Annotations: map[string]string{"config": "none"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		active, err := config.ReadProfile(cfgFile, activeProfile())
+		if err != nil {
+			return fmt.Errorf("loading config: %w", err)
+		}
+
+		var profiles []profileInfo
+		for _, name := range active.ProfileNames() {
+			c, err := config.ReadProfile(cfgFile, name)
+			if err != nil {
+				return fmt.Errorf("loading config: %w", err)
+			}
+			profiles = append(profiles, profileInfo{Name: name, Active: name == active.Profile, Config: c})
+		}
+
+		if len(profiles) == 0 && opts.Format == output.Table {
+			fmt.Printf("No profiles in %s\n", cfgFile)
+			return nil
+		}
+		return output.Render(os.Stdout, opts, profileColumns, profiles)
+	},
+}
+
+type profileInfo struct {
+	Name   string
+	Active bool
+	Config *config.Configuration
+}
+
+var profileColumns = []output.Column[profileInfo]{
+	{Name: "name", Value: func(p profileInfo) any { return p.Name }},
+	{Name: "active", Value: func(p profileInfo) any { return p.Active }},
+	{Name: "base_url", Value: func(p profileInfo) any { return p.Config.BaseURL }},
+	{Name: "web_user", Value: func(p profileInfo) any { return p.Config.WebUser }},
+	{Name: "db_host", Value: func(p profileInfo) any { return p.Config.DBHost }},
+	{Name: "db_port", Hidden: true, Value: func(p profileInfo) any { return p.Config.DBPort }},
+	{Name: "db_name", Value: func(p profileInfo) any { return p.Config.DBName }},
+	{Name: "output_dir", Value: func(p profileInfo) any { return p.Config.OutputDir }},
+}
+
+// activeProfile returns the profile selected by --profile or
+// EUSURVEYMGR_PROFILE; empty means the file's default_profile.
+func activeProfile() string {
+	if profile != "" {
+		return profile
+	}
+	return os.Getenv("EUSURVEYMGR_PROFILE")
+}
+
+func init() {
+	addOutputFlags(configProfilesCmd)
+	configShowCmd.Flags().Bool("origin", false, "Show where each value came from")
+	addOutputFlags(configShowCmd)
+	configInitCmd.Flags().String("output", "", "File to write (default: the --config path)")
+	configInitCmd.Flags().Bool("force", false, "Overwrite an existing file")
+
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configShowCmd)
+	configCmd.AddCommand(configProfilesCmd)
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"eusurveymgr/config"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var configSecretsCmd = &cobra.Command{
+	Use:   "secrets",
+	Short: "Manage the encrypted secrets file",
+	Long: `Manage the encrypted secrets file named by secrets_file in the config. It
+holds any of web_password, db_password, db_admin_password, pseudonym_key,
+api_tokens, ui_users.<username>.password and hooks.<name>.headers, encrypted with AES-256-GCM under a key derived from a passphrase
+(PBKDF2-SHA256). The passphrase is read from EUSURVEYMGR_SECRETS_PASSPHRASE,
+or asked for on the terminal.
+
+A secret in the secrets file is used when the config sets neither the value
+nor its _file or _command key, and no EUSURVEYMGR_* variable overrides it.`,
+}
+
+var configSecretsListCmd = &cobra.Command{
+	Use:     "list",
+	Short:   "List the keys stored in the secrets file",
+	Example: "  eusurveymgr config secrets list",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, secrets, err := openSecretsFile()
+		if err != nil {
+			return err
+		}
+		if len(secrets) == 0 {
+			fmt.Printf("No secrets in %s\n", path)
+			return nil
+		}
+		keys := make([]string, 0, len(secrets))
+		for k := range secrets {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("%s  (used: %s)\n", k, usedSecret(k, path))
+		}
+		return nil
+	},
+}
+
+var configSecretsSetCmd = &cobra.Command{
+	Use:   "set <key>",
+	Short: "Store a secret in the secrets file",
+	Long: `Store a secret in the secrets file, creating the file if needed. The value is
+asked for on the terminal without echo, or read from the first line of
+standard input when it is not a terminal. api_tokens takes a comma-separated
+list, hooks.<name>.headers one "Name: value" header.`,
+	Example: `  eusurveymgr config secrets set db_password
+  echo "Authorization: Bearer $TOKEN" | eusurveymgr config secrets set hooks.crm.headers
+  pass show eusurvey/web | eusurveymgr config secrets set web_password`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key := args[0]
+		if !config.IsSecretKey(key) {
+			return fmt.Errorf("unknown secret %q (use one of %s, ui_users.<username>.password or hooks.<name>.headers)", key, strings.Join(config.SecretKeys(), ", "))
+		}
+		path, secrets, err := openSecretsFile()
+		if err != nil {
+			return err
+		}
+		value, err := readSecret(key)
+		if err != nil {
+			return err
+		}
+		if value == "" {
+			return fmt.Errorf("empty value")
+		}
+		passphrase, err := secretsPassphrase(path)
+		if err != nil {
+			return err
+		}
+		secrets[key] = value
+		if err := config.WriteSecretsFile(path, passphrase, secrets); err != nil {
+			return err
+		}
+		fmt.Printf("Stored %s in %s\n", key, path)
+		return nil
+	},
+}
+
+var configSecretsRemoveCmd = &cobra.Command{
+	Use:     "remove <key>",
+	Short:   "Remove a secret from the secrets file",
+	Example: "  eusurveymgr config secrets remove pseudonym_key",
+	Args:    cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, secrets, err := openSecretsFile()
+		if err != nil {
+			return err
+		}
+		if _, ok := secrets[args[0]]; !ok {
+			return fmt.Errorf("%s is not in %s", args[0], path)
+		}
+		passphrase, err := secretsPassphrase(path)
+		if err != nil {
+			return err
+		}
+		delete(secrets, args[0])
+		if err := config.WriteSecretsFile(path, passphrase, secrets); err != nil {
+			return err
+		}
+		fmt.Printf("Removed %s from %s\n", args[0], path)
+		return nil
+	},
+}
+
+// openSecretsFile decrypts the configured secrets file; a file that does not
+// exist yet yields no secrets.
+func openSecretsFile() (string, map[string]string, error) {
+	path := cfg.SecretsFile
+	if path == "" {
+		return "", nil, fmt.Errorf("secrets_file is not set in %s", cfgFile)
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return path, map[string]string{}, nil
+	}
+	passphrase, err := config.Passphrase(path)
+	if err != nil {
+		return "", nil, err
+	}
+	secrets, err := config.ReadSecretsFile(path, passphrase)
+	if err != nil {
+		return "", nil, err
+	}
+	return path, secrets, nil
+}
+
+// secretsPassphrase returns the passphrase for writing path. For a new file
+// typed on the terminal it is asked for twice.
+func secretsPassphrase(path string) (string, error) {
+	if _, err := os.Stat(path); err == nil || os.Getenv("EUSURVEYMGR_SECRETS_PASSPHRASE") != "" {
+		return config.Passphrase(path)
+	}
+	first, err := promptPassphrase(path)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+	second, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if first != string(second) {
+		return "", fmt.Errorf("passphrases do not match")
+	}
+	return first, nil
+}
+
+// usedSecret reports whether the loaded config takes key from the secrets
+// file at path, or where it comes from instead.
+func usedSecret(key, path string) string {
+	origin := cfg.Origin(key)
+	if origin == "secrets_file "+path {
+		return "yes"
+	}
+	return "no, " + origin
+}
+
+func readSecret(key string) (string, error) {
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		fmt.Fprintf(os.Stderr, "%s: ", key)
+		value, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return strings.TrimSpace(string(value)), err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if line == "" && err != nil {
+		return "", fmt.Errorf("reading %s: %w", key, err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// promptPassphrase asks for the passphrase of a secrets file on the
+// terminal; it is config.PassphraseFunc when no environment variable is set.
+func promptPassphrase(path string) (string, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("set EUSURVEYMGR_SECRETS_PASSPHRASE to unlock %s", path)
+	}
+	fmt.Fprintf(os.Stderr, "Passphrase for %s: ", path)
+	p, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(p), nil
+}
+
+func init() {
+	envPassphrase := config.PassphraseFunc
+	config.PassphraseFunc = func(path string) (string, error) {
+		if os.Getenv("EUSURVEYMGR_SECRETS_PASSPHRASE") != "" {
+			return envPassphrase(path)
+		}
+		return promptPassphrase(path)
+	}
+
+	configSecretsCmd.AddCommand(configSecretsListCmd)
+	configSecretsCmd.AddCommand(configSecretsSetCmd)
+	configSecretsCmd.AddCommand(configSecretsRemoveCmd)
+	configCmd.AddCommand(configSecretsCmd)
+}
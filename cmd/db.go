@@ -1,20 +1,81 @@
 package cmd
 
 import (
-	"encoding/json"
+	"context"
+	"eusurveymgr/config"
 	"eusurveymgr/db"
 	"eusurveymgr/log"
+	"eusurveymgr/output"
+	"eusurveymgr/pseudo"
+	"eusurveymgr/watch"
 	"fmt"
 	"os"
-	"text/tabwriter"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 )
 
+// RepositoryFactory opens the SurveyRepository used by the db-backed
+// commands.
+type RepositoryFactory func(cfg *config.Configuration) (db.SurveyRepository, error)
+
+var repositoryFactory RepositoryFactory = defaultRepository
+
+// SetRepositoryFactory replaces how commands obtain their repository, so
+// tools embedding the command tree can inject their own (for example a
+// db.MemoryRepository in tests).
+func SetRepositoryFactory(f RepositoryFactory) {
+	repositoryFactory = f
+}
+
+func openRepository() (db.SurveyRepository, error) {
+	return repositoryFactory(cfg)
+}
+
+// defaultRepository selects the backend from --source: a JSON fixture, a
+// SQLite snapshot, or (without --source) the configured MySQL server.
+func defaultRepository(cfg *config.Configuration) (db.SurveyRepository, error) {
+	switch {
+	case strings.HasSuffix(dbSource, ".json"):
+		fixture, err := db.LoadFixture(dbSource)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewMemoryRepository(fixture), nil
+	case dbSource != "":
+		dbconn, err := db.OpenSnapshot(dbSource)
+		if err != nil {
+			return nil, err
+		}
+		return db.NewSQLRepository(dbconn), nil
+	}
+	dbconn, err := db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to MySQL: %w", err)
+	}
+	return db.NewSQLRepository(dbconn), nil
+}
+
 var dbCmd = &cobra.Command{
 	Use:   "db",
 	Short: "Query the MySQL database directly",
-	Long:  "Query the EUSurvey MySQL database directly for surveys, answer sets, and responses.",
+	Long: `Query the EUSurvey MySQL database directly for surveys, answer sets, and responses.
+
+With --source, every db command runs against a SQLite snapshot created by
+'db snapshot' (or a JSON fixture file) instead, without needing MySQL access.`,
+}
+
+var dbSurveyColumns = []output.Column[db.SurveyRow]{
+	{Name: "id", Value: func(s db.SurveyRow) any { return s.SurveyID }},
+	{Name: "uid", Width: 8, Value: func(s db.SurveyRow) any { return s.SurveyUID }},
+	{Name: "alias", Value: func(s db.SurveyRow) any { return s.Alias }},
+	{Name: "title", Width: 33, Value: func(s db.SurveyRow) any { return s.Title }},
+	{Name: "published", Value: func(s db.SurveyRow) any { return s.Published }},
+	{Name: "num_answers", Value: func(s db.SurveyRow) any { return s.NumAnswers }},
+	{Name: "created", Value: func(s db.SurveyRow) any { return s.Created }},
 }
 
 var dbSurveysCmd = &cobra.Command{
@@ -22,190 +83,231 @@ var dbSurveysCmd = &cobra.Command{
 	Short: "List surveys from MySQL",
 	Long:  "List all surveys from MySQL (latest version per SURVEY_UID, deduplicated).",
 	Example: `  eusurveymgr db surveys
-  eusurveymgr db surveys --json`,
+  eusurveymgr db surveys --json
+  eusurveymgr db surveys --columns id,alias,num_answers --format csv`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		jsonOut, _ := cmd.Flags().GetBool("json")
-
-		dbconn, err := db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+		opts, err := outputOptions(cmd)
 		if err != nil {
-			return fmt.Errorf("connecting to MySQL: %w", err)
+			return err
 		}
-		defer dbconn.Close()
 
-		surveys, err := db.ListSurveys(dbconn)
+		repo, err := openRepository()
 		if err != nil {
 			return err
 		}
+		defer repo.Close()
 
-		if jsonOut {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			return enc.Encode(surveys)
-		}
-
-		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-		fmt.Fprintln(w, "ID\tUID\tALIAS\tTITLE\tPUB\tANS\tCREATED")
-		for _, s := range surveys {
-			created := ""
-			if s.Created.Valid {
-				created = s.Created.String
-			}
-			title := s.Title
-			if len(title) > 32 {
-				title = title[:32] + "…"
-			}
-			fmt.Fprintf(w, "%d\t%.8s\t%s\t%s\t%v\t%d\t%s\n",
-				s.SurveyID, s.SurveyUID, s.Alias, title, s.Published, s.NumAnswers, created)
+		surveys, err := repo.ListSurveys()
+		if err != nil {
+			return err
 		}
-		return w.Flush()
+		return output.Render(os.Stdout, opts, dbSurveyColumns, surveys)
 	},
 }
 
+// answerSetColumns are shared by the listing and --follow. The survey ID is
+// only part of the structured formats, as in the hook and watch events.
+func answerSetColumns(surveyID int64) []output.Column[db.AnswerSetRow] {
+	return []output.Column[db.AnswerSetRow]{
+		{Name: "survey_id", Hidden: true, Value: func(db.AnswerSetRow) any { return surveyID }},
+		{Name: "answer_set_id", Width: 13, Value: func(a db.AnswerSetRow) any { return a.AnswerSetID }},
+		{Name: "uniquecode", Width: 36, Value: func(a db.AnswerSetRow) any { return a.UniqueCode }},
+		{Name: "date", Width: 19, Value: func(a db.AnswerSetRow) any { return a.Date }},
+		{Name: "name", Width: 30, Value: func(a db.AnswerSetRow) any { return a.Name }},
+		{Name: "email", Value: func(a db.AnswerSetRow) any { return a.Email }},
+	}
+}
+
 var dbAnswersCmd = &cobra.Command{
 	Use:   "answers",
 	Short: "List answer sets for a survey",
-	Long:  "List all answer sets (respondents) for a survey, showing name and email.",
+	Long: `List all answer sets (respondents) for a survey, showing name and email.
+
+With --follow the command keeps running like 'tail -f': it prints the existing
+answer sets oldest first, then polls every --interval and prints each new
+respondent once, until interrupted. Table, ndjson, csv and template output can
+be followed; --ndjson (one JSON object per answer set and line) is the one to
+pipe into other tools.`,
 	Example: `  eusurveymgr db answers --survey 4578
-  eusurveymgr db answers --survey 4609 --json`,
+  eusurveymgr db answers --survey 4609 --json
+  eusurveymgr db answers --survey 4609 --follow
+  eusurveymgr db answers --survey 4609 --follow --interval 5s --ndjson
+  eusurveymgr db answers --survey 4609 --template '{{.uniquecode}} {{.email}}'`,
+	Annotations: map[string]string{"daemon": "--follow"},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		surveyID, _ := cmd.Flags().GetInt64("survey")
-		jsonOut, _ := cmd.Flags().GetBool("json")
-
-		dbconn, err := db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+		follow, _ := cmd.Flags().GetBool("follow")
+		interval, _ := cmd.Flags().GetDuration("interval")
+		opts, err := outputOptions(cmd)
 		if err != nil {
-			return fmt.Errorf("connecting to MySQL: %w", err)
+			return err
 		}
-		defer dbconn.Close()
-
-		answers, err := db.ListAnswerSets(dbconn, surveyID)
+		p, err := identityPseudonymizer()
 		if err != nil {
 			return err
 		}
 
-		if jsonOut {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			return enc.Encode(answers)
+		repo, err := openRepository()
+		if err != nil {
+			return err
 		}
+		defer repo.Close()
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-		fmt.Fprintln(w, "ANSWER_SET_ID\tUNIQUECODE\tDATE\tNAME\tEMAIL")
-		for _, a := range answers {
-			date := ""
-			if a.Date.Valid {
-				date = a.Date.String
+		if follow {
+			if interval <= 0 {
+				return fmt.Errorf("--interval must be positive")
 			}
-			name := ""
-			if a.Name.Valid {
-				name = a.Name.String
-			}
-			email := ""
-			if a.Email.Valid {
-				email = a.Email.String
-			}
-			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n",
-				a.AnswerSetID, a.UniqueCode, date, name, email)
+			return followAnswerSets(repo, surveyID, interval, opts, p)
 		}
-		log.Infof("Total: %d answer sets", len(answers))
-		return w.Flush()
+
+		answers, err := repo.ListAnswerSets(surveyID)
+		if err != nil {
+			return err
+		}
+		for i := range answers {
+			answers[i].Name = p.NullString(answers[i].Name)
+			answers[i].Email = p.NullString(answers[i].Email)
+		}
+
+		if err := output.Render(os.Stdout, opts, answerSetColumns(surveyID), answers); err != nil {
+			return err
+		}
+		log.With("survey", surveyID).Infof("Total: %d answer sets", len(answers))
+		return nil
 	},
 }
 
+// followAnswerSets streams the answer sets of a survey until interrupted.
+// Rows are printed as they arrive, so the table uses fixed column widths
+// instead of being aligned at the end.
+func followAnswerSets(repo db.SurveyRepository, surveyID int64, interval time.Duration, opts output.Options, p *pseudo.Pseudonymizer) error {
+	out, err := output.NewStream(os.Stdout, opts, answerSetColumns(surveyID))
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := &watch.Watcher{Repo: repo, SurveyID: surveyID, Interval: interval}
+	return w.Run(ctx, func(a db.AnswerSetRow) error {
+		a.Name = p.NullString(a.Name)
+		a.Email = p.NullString(a.Email)
+		return out.Write(a)
+	})
+}
+
+type uniqueCodeRow struct {
+	AnswerSetID int64
+	UniqueCode  string
+}
+
+var uniqueCodeColumns = []output.Column[uniqueCodeRow]{
+	{Name: "answer_set_id", Value: func(r uniqueCodeRow) any { return r.AnswerSetID }},
+	{Name: "uniquecode", Value: func(r uniqueCodeRow) any { return r.UniqueCode }},
+}
+
 var dbLookupCmd = &cobra.Command{
-	Use:     "lookup",
-	Short:   "Look up UNIQUECODE by email",
-	Long:    "Look up the ANSWER_SET_ID and UNIQUECODE for a respondent by email address.",
-	Example: "  eusurveymgr db lookup --email user@example.com --survey 4578",
+	Use:   "lookup",
+	Short: "Look up UNIQUECODE by email",
+	Long:  "Look up the ANSWER_SET_ID and UNIQUECODE for a respondent by email address.",
+	Example: `  eusurveymgr db lookup --email user@example.com --survey 4578
+  eusurveymgr db lookup --email user@example.com --survey 4578 --template '{{.uniquecode}}'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		email, _ := cmd.Flags().GetString("email")
 		surveyID, _ := cmd.Flags().GetInt64("survey")
-
-		dbconn, err := db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+		opts, err := outputOptions(cmd)
 		if err != nil {
-			return fmt.Errorf("connecting to MySQL: %w", err)
+			return err
 		}
-		defer dbconn.Close()
 
-		answerSetID, uniqueCode, err := db.LookupUniqueCode(dbconn, email, surveyID)
+		repo, err := openRepository()
 		if err != nil {
 			return err
 		}
+		defer repo.Close()
 
-		fmt.Printf("ANSWER_SET_ID: %d\n", answerSetID)
-		fmt.Printf("UNIQUECODE:    %s\n", uniqueCode)
-		return nil
+		answerSetID, uniqueCode, err := repo.LookupUniqueCode(email, surveyID)
+		if err != nil {
+			return err
+		}
+		return output.RenderRecord(os.Stdout, opts, uniqueCodeColumns,
+			uniqueCodeRow{AnswerSetID: answerSetID, UniqueCode: uniqueCode})
 	},
 }
 
+var responseColumns = []output.Column[db.ResponseRow]{
+	{Name: "pa_id", Value: func(r db.ResponseRow) any { return r.PA_ID }},
+	{Name: "question", Width: 41, Value: func(r db.ResponseRow) any { return r.Question }},
+	{Name: "value", Value: func(r db.ResponseRow) any { return r.Value }},
+}
+
 var dbResponsesCmd = &cobra.Command{
 	Use:   "responses",
 	Short: "Show answers for a respondent",
 	Long:  "Show all answer values for a respondent, identified by --email and --survey.",
 	Example: `  eusurveymgr db responses --email user@example.com --survey 4578
-  eusurveymgr db responses --email user@example.com --survey 4578 --json`,
+  eusurveymgr db responses --email user@example.com --survey 4578 --json
+  eusurveymgr db responses --email user@example.com --survey 4578 --format yaml`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		email, _ := cmd.Flags().GetString("email")
 		surveyID, _ := cmd.Flags().GetInt64("survey")
-		jsonOut, _ := cmd.Flags().GetBool("json")
-
-		dbconn, err := db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+		opts, err := outputOptions(cmd)
 		if err != nil {
-			return fmt.Errorf("connecting to MySQL: %w", err)
+			return err
+		}
+		p, err := identityPseudonymizer()
+		if err != nil {
+			return err
 		}
-		defer dbconn.Close()
 
-		answerSetID, _, err := db.LookupUniqueCode(dbconn, email, surveyID)
+		repo, err := openRepository()
 		if err != nil {
 			return err
 		}
+		defer repo.Close()
 
-		responses, err := db.GetResponses(dbconn, answerSetID)
+		answerSetID, _, err := repo.LookupUniqueCode(email, surveyID)
 		if err != nil {
 			return err
 		}
 
-		if jsonOut {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			return enc.Encode(responses)
+		responses, err := repo.GetResponses(answerSetID)
+		if err != nil {
+			return err
 		}
+		pseudonymizeResponses(p, responses)
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-		fmt.Fprintln(w, "PA_ID\tQUESTION\tVALUE")
-		for _, r := range responses {
-			question := ""
-			if r.Question.Valid {
-				question = r.Question.String
-				if len(question) > 40 {
-					question = question[:40] + "…"
-				}
-			}
-			value := ""
-			if r.Value.Valid {
-				value = r.Value.String
-			}
-			fmt.Fprintf(w, "%d\t%s\t%s\n", r.PA_ID, question, value)
+		if err := output.Render(os.Stdout, opts, responseColumns, responses); err != nil {
+			return err
 		}
 		log.Infof("Total: %d answers (ANSWER_SET_ID=%d)", len(responses), answerSetID)
-		return w.Flush()
+		return nil
 	},
 }
 
 func init() {
-	dbSurveysCmd.Flags().Bool("json", false, "JSON output")
+	addOutputFlags(dbSurveysCmd)
 
 	dbAnswersCmd.Flags().Int64("survey", 0, "Survey ID")
-	dbAnswersCmd.Flags().Bool("json", false, "JSON output")
+	addOutputFlags(dbAnswersCmd)
+	dbAnswersCmd.Flags().Bool("ndjson", false, "Shorthand for --format ndjson")
+	dbAnswersCmd.Flags().BoolP("follow", "f", false, "Keep polling and print new answer sets as they arrive")
+	dbAnswersCmd.Flags().Duration("interval", 10*time.Second, "Polling interval for --follow")
+	dbAnswersCmd.MarkFlagsMutuallyExclusive("json", "ndjson")
+	dbAnswersCmd.MarkFlagsMutuallyExclusive("json", "follow")
 	dbAnswersCmd.MarkFlagRequired("survey")
 
 	dbLookupCmd.Flags().String("email", "", "Email address to look up")
 	dbLookupCmd.Flags().Int64("survey", 0, "Survey ID")
+	addOutputFlags(dbLookupCmd)
 	dbLookupCmd.MarkFlagRequired("email")
 	dbLookupCmd.MarkFlagRequired("survey")
 
 	dbResponsesCmd.Flags().String("email", "", "Respondent email address")
 	dbResponsesCmd.Flags().Int64("survey", 0, "Survey ID")
-	dbResponsesCmd.Flags().Bool("json", false, "JSON output")
+	addOutputFlags(dbResponsesCmd)
 	dbResponsesCmd.MarkFlagRequired("email")
 	dbResponsesCmd.MarkFlagRequired("survey")
 
@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"eusurveymgr/analysis"
+	"eusurveymgr/output"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var dbDistributionCmd = &cobra.Command{
+	Use:   "distribution",
+	Short: "Per-question answer distributions and statistics",
+	Long: `Compute per-question answer distributions for a survey.
+
+Choice and Likert questions get a frequency table (count and percentage of
+respondents who answered, plus missing). Numeric questions get min/max/mean/
+median/stdev; free-text questions get the same statistics over answer length
+in characters. A matrix is reported row by row; --question with its UID
+selects all rows.
+
+--format json or yaml encodes the distributions as they are. csv, ndjson,
+template and --columns flatten them into one row per frequency or statistic.`,
+	Example: `  eusurveymgr db distribution --survey 4609
+  eusurveymgr db distribution --survey 4578 --question 5c2e9b1a-... --json
+  eusurveymgr db distribution --survey 4609 --csv > distribution.csv
+  eusurveymgr db distribution --survey 4609 --columns question,item,count,percent`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		surveyID, _ := cmd.Flags().GetInt64("survey")
+		questionUID, _ := cmd.Flags().GetString("question")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		repo, err := openRepository()
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+
+		dataset, err := analysis.LoadDataset(repo, surveyID)
+		if err != nil {
+			return err
+		}
+		dists := analysis.Distributions(dataset, questionUID)
+		if questionUID != "" && len(dists) == 0 {
+			return fmt.Errorf("question %q not found in survey %d", questionUID, surveyID)
+		}
+
+		if opts.Structured() {
+			return output.Encode(os.Stdout, opts.Format, dists)
+		}
+		if opts.Format != output.Table || len(opts.Columns) > 0 {
+			return output.Render(os.Stdout, opts, distributionColumns, distributionRows(dists))
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for i, d := range dists {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "%s [%.8s] (%s, %s)\n", d.Question, d.QuestionUID, d.Kind, d.Type)
+			fmt.Fprintf(w, "  respondents: %d  answered: %d  missing: %d (%.1f%%)\n",
+				d.Respondents, d.Answered, d.Missing, d.MissingPercent)
+			switch {
+			case d.Frequencies != nil:
+				fmt.Fprintln(w, "  OPTION\tCOUNT\tPERCENT")
+				for _, f := range d.Frequencies {
+					fmt.Fprintf(w, "  %s\t%d\t%.1f%%\n", f.Label, f.Count, f.Percent)
+				}
+			case d.Numeric != nil:
+				printDescriptive(w, "value", d.Numeric)
+				if d.Invalid > 0 {
+					fmt.Fprintf(w, "  non-numeric answers: %d\n", d.Invalid)
+				}
+			case d.Length != nil:
+				printDescriptive(w, "length", d.Length)
+			}
+		}
+		return w.Flush()
+	},
+}
+
+func printDescriptive(w *tabwriter.Writer, label string, s *analysis.Descriptive) {
+	fmt.Fprintln(w, "  STAT\tN\tMIN\tMAX\tMEAN\tMEDIAN\tSTDEV")
+	fmt.Fprintf(w, "  %s\t%d\t%g\t%g\t%.2f\t%g\t%.2f\n",
+		label, s.N, s.Min, s.Max, s.Mean, s.Median, s.StdDev)
+}
+
+// distributionRow is one frequency or statistic of a question, so that the
+// whole survey fits in a single flat table.
+type distributionRow struct {
+	analysis.Distribution
+	Item    string
+	Count   any
+	Percent any
+	Value   any
+}
+
+var distributionColumns = []output.Column[distributionRow]{
+	{Name: "question_uid", Value: func(r distributionRow) any { return r.QuestionUID }},
+	{Name: "question", Width: 40, Value: func(r distributionRow) any { return r.Question }},
+	{Name: "kind", Value: func(r distributionRow) any { return r.Kind }},
+	{Name: "item", Value: func(r distributionRow) any { return r.Item }},
+	{Name: "count", Value: func(r distributionRow) any { return r.Count }},
+	{Name: "percent", Value: func(r distributionRow) any { return r.Percent }},
+	{Name: "value", Value: func(r distributionRow) any { return r.Value }},
+}
+
+func distributionRows(dists []analysis.Distribution) []distributionRow {
+	var rows []distributionRow
+	for _, d := range dists {
+		row := func(item string, count, pct, value any) {
+			rows = append(rows, distributionRow{Distribution: d, Item: item, Count: count, Percent: pct, Value: value})
+		}
+		row("(answered)", d.Answered, nil, nil)
+		row("(missing)", d.Missing, round4(d.MissingPercent), nil)
+		for _, f := range d.Frequencies {
+			row(f.Label, f.Count, round4(f.Percent), nil)
+		}
+		stats := d.Numeric
+		prefix := ""
+		if d.Length != nil {
+			stats = d.Length
+			prefix = "length_"
+		}
+		if stats != nil {
+			row(prefix+"n", stats.N, nil, nil)
+			row(prefix+"min", nil, nil, round4(stats.Min))
+			row(prefix+"max", nil, nil, round4(stats.Max))
+			row(prefix+"mean", nil, nil, round4(stats.Mean))
+			row(prefix+"median", nil, nil, round4(stats.Median))
+			row(prefix+"stdev", nil, nil, round4(stats.StdDev))
+		}
+		if d.Invalid > 0 {
+			row("(invalid)", d.Invalid, nil, nil)
+		}
+	}
+	return rows
+}
+
+// round4 keeps at most four decimals for spreadsheet import.
+func round4(f float64) float64 {
+	return math.Round(f*10000) / 10000
+}
+
+func init() {
+	dbDistributionCmd.Flags().Int64("survey", 0, "Survey ID")
+	dbDistributionCmd.Flags().String("question", "", "Only this question (ELEM_UID)")
+	addOutputFlags(dbDistributionCmd)
+	dbDistributionCmd.Flags().Bool("csv", false, "Shorthand for --format csv")
+	dbDistributionCmd.MarkFlagRequired("survey")
+	dbDistributionCmd.MarkFlagsMutuallyExclusive("json", "csv")
+
+	dbCmd.AddCommand(dbDistributionCmd)
+}
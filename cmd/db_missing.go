@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"eusurveymgr/analysis"
+	"eusurveymgr/log"
+	"eusurveymgr/output"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var dbMissingCmd = &cobra.Command{
+	Use:   "missing",
+	Short: "Missing-data and completeness report",
+	Long: `Report which questions each respondent left unanswered.
+
+By default prints per-question skip rates followed by one line per respondent
+with answered/total counts and completeness. Respondents below --threshold
+(percent) are flagged. --items lists each respondent's unanswered required
+and optional questions; --matrix prints the full respondent × question
+matrix (1 = answered, 0 = missing) instead. Each row of a matrix question
+counts as a question of its own.
+
+--format json or yaml encodes the whole report. csv, ndjson, template and
+--columns write one row per respondent: the completeness summary, or with
+--matrix one column per question UID.`,
+	Example: `  eusurveymgr db missing --survey 4609
+  eusurveymgr db missing --survey 4609 --threshold 90 --flagged --items
+  eusurveymgr db missing --survey 4578 --matrix --csv > completeness.csv
+  eusurveymgr db missing --survey 4578 --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		surveyID, _ := cmd.Flags().GetInt64("survey")
+		threshold, _ := cmd.Flags().GetFloat64("threshold")
+		flaggedOnly, _ := cmd.Flags().GetBool("flagged")
+		showItems, _ := cmd.Flags().GetBool("items")
+		showMatrix, _ := cmd.Flags().GetBool("matrix")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+		p, err := identityPseudonymizer()
+		if err != nil {
+			return err
+		}
+
+		repo, err := openRepository()
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+
+		dataset, err := analysis.LoadDataset(repo, surveyID)
+		if err != nil {
+			return err
+		}
+		report := analysis.Missing(dataset, threshold)
+		for i := range report.Respondents {
+			report.Respondents[i].Name = p.Apply(report.Respondents[i].Name)
+			report.Respondents[i].Email = p.Apply(report.Respondents[i].Email)
+		}
+
+		flagged := 0
+		for _, r := range report.Respondents {
+			if r.BelowThreshold {
+				flagged++
+			}
+		}
+		if flaggedOnly {
+			var kept []analysis.Respondent
+			for _, r := range report.Respondents {
+				if r.BelowThreshold {
+					kept = append(kept, r)
+				}
+			}
+			report.Respondents = kept
+		}
+
+		if opts.Structured() {
+			return output.Encode(os.Stdout, opts.Format, report)
+		}
+		if opts.Format != output.Table || len(opts.Columns) > 0 {
+			if showMatrix {
+				return output.Render(os.Stdout, opts, matrixColumns(report.Items), report.Respondents)
+			}
+			return output.Render(os.Stdout, opts, completenessColumns, report.Respondents)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		if showMatrix {
+			header := []string{"ANSWER_SET_ID", "EMAIL"}
+			for i := range report.Items {
+				header = append(header, "Q"+strconv.Itoa(i+1))
+			}
+			fmt.Fprintln(w, strings.Join(header, "\t"))
+			for _, r := range report.Respondents {
+				cells := []string{strconv.FormatInt(r.AnswerSetID, 10), r.Email}
+				for _, answered := range r.Matrix {
+					cells = append(cells, matrixCell(answered))
+				}
+				fmt.Fprintln(w, strings.Join(cells, "\t"))
+			}
+			fmt.Fprintln(w)
+			for i, item := range report.Items {
+				fmt.Fprintf(w, "Q%d\t%.8s\t%s\n", i+1, item.UID, item.Title)
+			}
+			return w.Flush()
+		}
+
+		fmt.Fprintln(w, "QUESTION\tUID\tREQUIRED\tANSWERED\tSKIPPED\tSKIP_RATE")
+		for _, s := range report.SkipRates {
+			fmt.Fprintf(w, "%s\t%.8s\t%v\t%d\t%d\t%.1f%%\n",
+				s.Title, s.UID, !s.Optional, s.Answered, s.Skipped, s.Rate)
+		}
+		fmt.Fprintln(w)
+		fmt.Fprintln(w, "ANSWER_SET_ID\tNAME\tEMAIL\tANSWERED\tCOMPLETE\tMISS_REQ\tMISS_OPT\tFLAG")
+		for _, r := range report.Respondents {
+			flag := ""
+			if r.BelowThreshold {
+				flag = "LOW"
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%d/%d\t%.1f%%\t%d\t%d\t%s\n",
+				r.AnswerSetID, r.Name, r.Email, r.Answered, r.Total, r.Completeness,
+				len(r.MissingRequired), len(r.MissingOptional), flag)
+		}
+		if showItems {
+			// Listed after the summary so the table columns stay aligned.
+			for _, r := range report.Respondents {
+				if len(r.MissingRequired)+len(r.MissingOptional) == 0 {
+					continue
+				}
+				fmt.Fprintf(w, "\n%d %s\n", r.AnswerSetID, r.Email)
+				for _, item := range r.MissingRequired {
+					fmt.Fprintf(w, "  required\t%.8s\t%s\n", item.UID, item.Title)
+				}
+				for _, item := range r.MissingOptional {
+					fmt.Fprintf(w, "  optional\t%.8s\t%s\n", item.UID, item.Title)
+				}
+			}
+		}
+		log.Infof("%d of %d respondents below %.0f%% completeness", flagged, len(dataset.AnswerSets), threshold)
+		return w.Flush()
+	},
+}
+
+func matrixCell(answered bool) string {
+	if answered {
+		return "1"
+	}
+	return "0"
+}
+
+func itemUIDs(items []analysis.Item) []string {
+	uids := make([]string, len(items))
+	for i, item := range items {
+		uids[i] = item.UID
+	}
+	return uids
+}
+
+// matrixColumns has one column per question, named by its UID.
+func matrixColumns(items []analysis.Item) []output.Column[analysis.Respondent] {
+	cols := []output.Column[analysis.Respondent]{
+		{Name: "answer_set_id", Value: func(r analysis.Respondent) any { return r.AnswerSetID }},
+		{Name: "uniquecode", Value: func(r analysis.Respondent) any { return r.UniqueCode }},
+		{Name: "email", Value: func(r analysis.Respondent) any { return r.Email }},
+	}
+	for i, item := range items {
+		cols = append(cols, output.Column[analysis.Respondent]{Name: item.UID,
+			Value: func(r analysis.Respondent) any { return matrixCell(r.Matrix[i]) }})
+	}
+	return cols
+}
+
+var completenessColumns = []output.Column[analysis.Respondent]{
+	{Name: "answer_set_id", Value: func(r analysis.Respondent) any { return r.AnswerSetID }},
+	{Name: "uniquecode", Value: func(r analysis.Respondent) any { return r.UniqueCode }},
+	{Name: "name", Value: func(r analysis.Respondent) any { return r.Name }},
+	{Name: "email", Value: func(r analysis.Respondent) any { return r.Email }},
+	{Name: "answered", Value: func(r analysis.Respondent) any { return r.Answered }},
+	{Name: "total", Value: func(r analysis.Respondent) any { return r.Total }},
+	{Name: "completeness", Value: func(r analysis.Respondent) any { return round4(r.Completeness) }},
+	{Name: "below_threshold", Value: func(r analysis.Respondent) any { return r.BelowThreshold }},
+	{Name: "missing_required", Value: func(r analysis.Respondent) any { return itemUIDs(r.MissingRequired) }},
+	{Name: "missing_optional", Value: func(r analysis.Respondent) any { return itemUIDs(r.MissingOptional) }},
+}
+
+func init() {
+	dbMissingCmd.Flags().Int64("survey", 0, "Survey ID")
+	dbMissingCmd.Flags().Float64("threshold", 80, "Flag respondents below this completeness (percent)")
+	dbMissingCmd.Flags().Bool("flagged", false, "Only show respondents below the threshold")
+	dbMissingCmd.Flags().Bool("items", false, "List each respondent's unanswered questions")
+	dbMissingCmd.Flags().Bool("matrix", false, "Print the respondent × question matrix")
+	addOutputFlags(dbMissingCmd)
+	dbMissingCmd.Flags().Bool("csv", false, "Shorthand for --format csv")
+	dbMissingCmd.MarkFlagRequired("survey")
+	dbMissingCmd.MarkFlagsMutuallyExclusive("json", "csv")
+
+	dbCmd.AddCommand(dbMissingCmd)
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"eusurveymgr/output"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// Settings that EUSurvey reads at startup: changing them needs a Tomcat
+// restart.
+var restartSettings = map[string]bool{
+	"DisableWebserviceAPI": true,
+}
+
+var dbSettingsCmd = &cobra.Command{
+	Use:   "settings",
+	Short: "Inspect and change the EUSurvey SETTINGS table",
+	Long: `Inspect and change the SETTINGS table, which holds EUSurvey runtime switches
+such as DisableWebserviceAPI (must be 'false' for the WebService API).
+
+list and get use the regular read-only connection. set opens a separate
+connection with db_admin_user/db_admin_password (falling back to
+db_user/db_password), shows the change, and asks for confirmation.`,
+}
+
+var settingColumns = []output.Column[db.SettingRow]{
+	{Name: "key", Value: func(s db.SettingRow) any { return s.Key }},
+	{Name: "value", Value: func(s db.SettingRow) any { return s.Value }},
+	{Name: "format", Value: func(s db.SettingRow) any { return s.Format }},
+}
+
+var dbSettingsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all settings",
+	Example: `  eusurveymgr db settings list
+  eusurveymgr db settings list --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		dbconn, err := connectSettings(false)
+		if err != nil {
+			return err
+		}
+		defer dbconn.Close()
+
+		settings, err := db.ListSettings(dbconn)
+		if err != nil {
+			return err
+		}
+		return output.Render(os.Stdout, opts, settingColumns, settings)
+	},
+}
+
+var dbSettingsGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "Show a setting",
+	Example: `  eusurveymgr db settings get DisableWebserviceAPI
+  eusurveymgr db settings get DisableWebserviceAPI --template '{{.value}}'`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		dbconn, err := connectSettings(false)
+		if err != nil {
+			return err
+		}
+		defer dbconn.Close()
+
+		s, err := db.GetSetting(dbconn, args[0])
+		if err != nil {
+			return err
+		}
+		return output.RenderRecord(os.Stdout, opts, settingColumns, *s)
+	},
+}
+
+var dbSettingsSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Change a setting (asks for confirmation)",
+	Long: `Change the value of a setting over a separate writable connection. The
+current and new row are shown as a diff and the change is made only after
+confirmation (or with -y), in a transaction that must change exactly one row.
+
+A key that does not exist yet is only inserted with --create. Values of
+settings whose format is "true / false" must be true or false.`,
+	Example: `  eusurveymgr db settings set DisableWebserviceAPI false
+  eusurveymgr db settings set DisableWebserviceAPI false --create --format "true / false" -y`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		key, value := args[0], args[1]
+		create, _ := cmd.Flags().GetBool("create")
+		format, _ := cmd.Flags().GetString("format")
+		yes, _ := cmd.Flags().GetBool("yes")
+
+		dbconn, err := connectSettings(true)
+		if err != nil {
+			return err
+		}
+		defer dbconn.Close()
+
+		before, err := db.GetSetting(dbconn, key)
+		switch {
+		case errors.Is(err, db.ErrSettingNotFound) && !create:
+			return fmt.Errorf("%w (use --create to insert it)", err)
+		case errors.Is(err, db.ErrSettingNotFound):
+			before = nil
+		case err != nil:
+			return err
+		case create:
+			return fmt.Errorf("%s already exists; omit --create to update it", key)
+		default:
+			format = before.Format.String
+			if before.Value.Valid && before.Value.String == value {
+				fmt.Printf("%s is already %q, nothing to change\n", key, value)
+				return nil
+			}
+		}
+		if strings.EqualFold(strings.ReplaceAll(format, " ", ""), "true/false") && value != "true" && value != "false" {
+			return fmt.Errorf("%s takes %q, got %q", key, format, value)
+		}
+
+		fmt.Printf("  SETTINGS_KEY     %s\n", key)
+		if before != nil {
+			fmt.Printf("- SETTINGS_VALUE   %s\n", before.Value.String)
+		}
+		fmt.Printf("+ SETTINGS_VALUE   %s\n", value)
+		if before == nil {
+			fmt.Printf("+ SETTINGS_FORMAT  %s\n", format)
+		}
+
+		if !yes {
+			fmt.Fprintf(os.Stderr, "\nApply this change to %s on %s? [y/N] ", cfg.DBName, cfg.DBHost)
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if strings.TrimSpace(strings.ToLower(answer)) != "y" {
+				return fmt.Errorf("aborted")
+			}
+		}
+
+		tx, err := dbconn.Begin()
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		if err := db.SetSetting(tx, key, value, format, before == nil); err != nil {
+			return err
+		}
+		after, err := db.GetSetting(tx, key)
+		if err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("committing setting %s: %w", key, err)
+		}
+		log.With("key", key).Infof("SETTINGS -- %s set to %q by %s", key, after.Value.String, settingsUser())
+
+		fmt.Printf("%s is now %q\n", key, after.Value.String)
+		if restartSettings[key] {
+			fmt.Println("EUSurvey reads this setting at startup: restart Tomcat for it to take effect.")
+		} else {
+			fmt.Println("If the change has no effect, restart Tomcat: EUSurvey may cache settings.")
+		}
+		return nil
+	},
+}
+
+// connectSettings connects to MySQL; writable selects the admin account.
+func connectSettings(writable bool) (*sql.DB, error) {
+	if dbSource != "" {
+		return nil, fmt.Errorf("SETTINGS is only available in MySQL and cannot be combined with --source")
+	}
+	user, password := cfg.DBUser, cfg.DBPassword
+	if writable {
+		user = settingsUser()
+		if cfg.DBAdminUser != "" {
+			password = cfg.DBAdminPassword
+		} else {
+			log.Warnf("SETTINGS -- db_admin_user not set, writing as %s", user)
+		}
+	}
+	dbconn, err := db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, user, password, cfg.DBName)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to MySQL: %w", err)
+	}
+	return dbconn, nil
+}
+
+func settingsUser() string {
+	if cfg.DBAdminUser != "" {
+		return cfg.DBAdminUser
+	}
+	return cfg.DBUser
+}
+
+func init() {
+	addOutputFlags(dbSettingsListCmd)
+	addOutputFlags(dbSettingsGetCmd)
+	dbSettingsSetCmd.Flags().Bool("create", false, "Insert the key if it does not exist")
+	dbSettingsSetCmd.Flags().String("format", "true / false", "With --create, the SETTINGS_FORMAT of the new key")
+	dbSettingsSetCmd.Flags().BoolP("yes", "y", false, "Skip confirmation prompt")
+
+	dbSettingsCmd.AddCommand(dbSettingsListCmd)
+	dbSettingsCmd.AddCommand(dbSettingsGetCmd)
+	dbSettingsCmd.AddCommand(dbSettingsSetCmd)
+	dbCmd.AddCommand(dbSettingsCmd)
+}
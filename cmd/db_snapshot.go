@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var dbSnapshotCmd = &cobra.Command{
+	Use:   "snapshot",
+	Short: "Copy a survey's data into a SQLite file",
+	Long: `Copy the SURVEYS, ANSWERS_SET, ANSWERS, ELEMENTS, SURVEYS_ELEMENTS and
+ELEMENTS_ELEMENTS rows of one survey from MySQL into a new SQLite file.
+
+Analysts without MySQL/SSH access can then run any db command against it
+with the global --source flag; no config file is needed for that.`,
+	Example: `  eusurveymgr db snapshot --survey 4609 --out survey-4609.sqlite
+  eusurveymgr --source survey-4609.sqlite db answers --survey 4609
+  eusurveymgr --source survey-4609.sqlite db distribution --survey 4609 --csv`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		surveyID, _ := cmd.Flags().GetInt64("survey")
+		outFile, _ := cmd.Flags().GetString("out")
+		if dbSource != "" {
+			return fmt.Errorf("db snapshot reads from MySQL and cannot be combined with --source")
+		}
+
+		dbconn, err := db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+		if err != nil {
+			return fmt.Errorf("connecting to MySQL: %w", err)
+		}
+		defer dbconn.Close()
+
+		if err := db.CreateSnapshot(dbconn, surveyID, outFile); err != nil {
+			return err
+		}
+		log.Infof("Snapshot of survey %d saved to %s", surveyID, outFile)
+		return nil
+	},
+}
+
+func init() {
+	dbSnapshotCmd.Flags().Int64("survey", 0, "Survey ID")
+	dbSnapshotCmd.Flags().String("out", "", "SQLite output file (must not exist)")
+	dbSnapshotCmd.MarkFlagRequired("survey")
+	dbSnapshotCmd.MarkFlagRequired("out")
+
+	dbCmd.AddCommand(dbSnapshotCmd)
+}
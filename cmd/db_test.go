@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"encoding/json"
+	"eusurveymgr/config"
+	"eusurveymgr/db"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var testFixture = &db.Fixture{Surveys: []db.FixtureSurvey{{
+	ID:    4609,
+	UID:   "survey-uid",
+	Alias: "Check4Test",
+	Title: "Test survey",
+	Elements: []db.FixtureElement{
+		{UID: "q-age", Title: "Age", Type: "NumberQuestion"},
+		{UID: "q-lang", Title: "Language", Type: "SingleChoiceQuestion", Options: []db.FixtureOption{
+			{UID: "o-ro", Title: "Română"},
+			{UID: "o-en", Title: "English"},
+		}},
+	},
+	AnswerSets: []db.FixtureAnswerSet{
+		{ID: 1, UniqueCode: "code-ana", Date: "2026-03-01 10:00:00", Answers: []db.FixtureAnswer{
+			{QuestionUID: "q-name", Value: "Ana Popescu"},
+			{QuestionUID: "q-age", PA_ID: 1, Value: "16"},
+			{QuestionUID: "q-lang", PA_ID: 2, PA_UID: "o-ro", Value: "Română"},
+			{QuestionUID: "q-email", Value: "ana.popescu@example.com"},
+		}},
+		{ID: 2, UniqueCode: "code-ion", Date: "2026-03-02 11:00:00", Answers: []db.FixtureAnswer{
+			{QuestionUID: "q-name", Value: "Ion Țurcanu"},
+			{QuestionUID: "q-email", Value: "ion.turcanu@example.com"},
+		}},
+	},
+}}}
+
+// runCommand runs the command line args against a MemoryRepository of
+// testFixture and returns what it wrote to stdout.
+func runCommand(t *testing.T, args ...string) string {
+	t.Helper()
+	return runCommandOn(t, testFixture, args...)
+}
+
+// runCommandOn is runCommand on fixture.
+func runCommandOn(t *testing.T, fixture *db.Fixture, args ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "config.json")
+	content := `{"pseudonym_key": "0123456789abcdef0123456789abcdef", "output_dir": "` + dir + `", "state_dir": "` + dir + `"}`
+	if err := os.WriteFile(cfgPath, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	SetRepositoryFactory(func(*config.Configuration) (db.SurveyRepository, error) {
+		return db.NewMemoryRepository(fixture), nil
+	})
+	t.Cleanup(func() {
+		SetRepositoryFactory(defaultRepository)
+		pseudonymize = false
+	})
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	done := make(chan []byte)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- out
+	}()
+
+	rootCmd.SetArgs(append([]string{"--config", cfgPath, "-q"}, args...))
+	err = rootCmd.Execute()
+	w.Close()
+	os.Stdout = stdout
+	out := <-done
+	if err != nil {
+		t.Fatalf("%s: %v", strings.Join(args, " "), err)
+	}
+	return string(out)
+}
+
+func TestDBAnswers(t *testing.T) {
+	out := runCommand(t, "db", "answers", "--survey", "4609", "--format", "json")
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("decoding %q: %v", out, err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("got %d answer sets, want 2", len(rows))
+	}
+	want := map[string]string{"code-ana": "Ana Popescu", "code-ion": "Ion Țurcanu"}
+	for _, row := range rows {
+		code, _ := row["uniquecode"].(string)
+		if name, ok := want[code]; !ok || row["name"] != name {
+			t.Errorf("answer set %v: got name %v, want %q", code, row["name"], name)
+		}
+	}
+}
+
+func TestDBAnswersPseudonymized(t *testing.T) {
+	out := runCommand(t, "db", "answers", "--survey", "4609", "--format", "csv", "--pseudonymize")
+	if strings.Contains(out, "Ana Popescu") || strings.Contains(out, "ana.popescu@example.com") {
+		t.Errorf("identities not pseudonymised:\n%s", out)
+	}
+	if strings.Count(out, "p-") != 4 {
+		t.Errorf("want 4 pseudonyms (2 names, 2 emails):\n%s", out)
+	}
+}
+
+func TestDBResponses(t *testing.T) {
+	out := runCommand(t, "db", "responses", "--email", "ana.popescu@example.com", "--survey", "4609", "--format", "json")
+	var rows []map[string]any
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("decoding %q: %v", out, err)
+	}
+	var values []string
+	for _, row := range rows {
+		v, _ := row["value"].(string)
+		values = append(values, v)
+	}
+	// Ordered by PA_ID, so the identity answers (PA_ID 0) come first.
+	want := []string{"Ana Popescu", "ana.popescu@example.com", "16", "Română"}
+	if strings.Join(values, "|") != strings.Join(want, "|") {
+		t.Errorf("got values %q, want %q", values, want)
+	}
+}
+
+// matrixFixture has a matrix whose answers are stored under its rows.
+var matrixFixture = &db.Fixture{Surveys: []db.FixtureSurvey{{
+	ID:    7,
+	UID:   "matrix-uid",
+	Alias: "MatrixTest",
+	Title: "Matrix survey",
+	Elements: []db.FixtureElement{
+		{UID: "q-use", Title: "How often do you use", Type: "MATRIX",
+			Options: []db.FixtureOption{{UID: "c-daily", Title: "Daily"}, {UID: "c-weekly", Title: "Weekly"}},
+			Rows:    []db.FixtureOption{{UID: "r-mail", Title: "E-mail"}, {UID: "r-web", Title: "The web"}}},
+	},
+	AnswerSets: []db.FixtureAnswerSet{
+		{ID: 1, UniqueCode: "code-1", Date: "2026-03-01 10:00:00", Answers: []db.FixtureAnswer{
+			{QuestionUID: "r-mail", PA_ID: 1, PA_UID: "c-daily", Value: "Daily"},
+			{QuestionUID: "r-web", PA_ID: 2, PA_UID: "c-weekly", Value: "Weekly"},
+		}},
+		{ID: 2, UniqueCode: "code-2", Date: "2026-03-02 10:00:00", Answers: []db.FixtureAnswer{
+			{QuestionUID: "r-mail", PA_ID: 1, PA_UID: "c-daily", Value: "Daily"},
+		}},
+	},
+}}}
+
+func TestDBDistributionMatrix(t *testing.T) {
+	out := runCommandOn(t, matrixFixture, "db", "distribution", "--survey", "7", "--format", "json")
+	var dists []struct {
+		QuestionUID string `json:"question_uid"`
+		MatrixUID   string `json:"matrix_uid"`
+		Answered    int    `json:"answered"`
+		Frequencies []struct {
+			Label string `json:"label"`
+			Count int    `json:"count"`
+		} `json:"frequencies"`
+	}
+	if err := json.Unmarshal([]byte(out), &dists); err != nil {
+		t.Fatalf("decoding %q: %v", out, err)
+	}
+	if len(dists) != 2 || dists[0].QuestionUID != "r-mail" || dists[1].QuestionUID != "r-web" {
+		t.Fatalf("want one distribution per matrix row:\n%s", out)
+	}
+	mail := dists[0]
+	if mail.MatrixUID != "q-use" || mail.Answered != 2 || len(mail.Frequencies) != 2 ||
+		mail.Frequencies[0].Label != "Daily" || mail.Frequencies[0].Count != 2 {
+		t.Errorf("row r-mail: got %+v", mail)
+	}
+}
+
+func TestDBMissingMatrix(t *testing.T) {
+	out := runCommandOn(t, matrixFixture, "db", "missing", "--survey", "7", "--format", "json")
+	var report struct {
+		Respondents []struct {
+			UniqueCode string `json:"uniquecode"`
+			Answered   int    `json:"answered"`
+			Total      int    `json:"total"`
+		} `json:"respondents"`
+		SkipRates []struct {
+			UID     string `json:"uid"`
+			Skipped int    `json:"skipped"`
+		} `json:"skip_rates"`
+	}
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("decoding %q: %v", out, err)
+	}
+	answered := make(map[string]int)
+	for _, r := range report.Respondents {
+		if r.Total != 2 {
+			t.Errorf("%s: total %d, want 2 matrix rows", r.UniqueCode, r.Total)
+		}
+		answered[r.UniqueCode] = r.Answered
+	}
+	if answered["code-1"] != 2 || answered["code-2"] != 1 {
+		t.Errorf("got answered %v, want code-1: 2, code-2: 1", answered)
+	}
+	skipped := make(map[string]int)
+	for _, s := range report.SkipRates {
+		skipped[s.UID] = s.Skipped
+	}
+	if len(skipped) != 2 || skipped["r-mail"] != 0 || skipped["r-web"] != 1 {
+		t.Errorf("got skip counts %v, want r-mail: 0, r-web: 1", skipped)
+	}
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"database/sql"
+	"eusurveymgr/db"
+	"eusurveymgr/doctor"
+	"eusurveymgr/output"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose the EUSurvey integration",
+	Long: `Check each layer the commands depend on, in order, and print pass/fail with
+a fix hint for every problem:
+
+  1. Reachability and TLS   GET /auth/login; certificate trust and expiry
+  2. WebService API         Basic-auth getMySurveys
+  3. Session login          CSRF meta tag and form login
+  4. Database               MySQL connection and SELECT grants
+  5. SETTINGS               DisableWebserviceAPI must be 'false'
+  6. Answer PDF             generate and download a canary answer PDF
+
+Checks that depend on a failed layer are skipped. The PDF round trip generates
+a PDF on the server for the test answer given by --canary; without it (or
+with --skip-pdf) it is skipped, so no real respondent's answer is used.
+Exits non-zero if any check fails.`,
+	Example: `  eusurveymgr doctor
+  eusurveymgr doctor --canary ae8d5fec-daaf-4aba-b860-544d1f717d8a
+  eusurveymgr --profile prod doctor --skip-pdf --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		canary, _ := cmd.Flags().GetString("canary")
+		skipPDF, _ := cmd.Flags().GetBool("skip-pdf")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		d := &doctor.Doctor{
+			Config:  cfg,
+			Client:  newClient(),
+			Canary:  canary,
+			SkipPDF: skipPDF,
+		}
+		if dbSource == "" && cfg.DBHost != "" {
+			d.ConnectDB = func() (*sql.DB, error) {
+				return db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+			}
+		}
+
+		report := func(r doctor.Result) {
+			fmt.Printf("%-6s %-32s %s (%dms)\n", "["+strings.ToUpper(string(r.Status))+"]", r.Check, r.Detail, r.DurationMS)
+			if r.Hint != "" {
+				fmt.Printf("       fix: %s\n", r.Hint)
+			}
+		}
+		// The table is the live report; the other formats render the results
+		// once all checks have run.
+		live := opts.Format == output.Table && len(opts.Columns) == 0
+		if !live {
+			report = nil
+		}
+		results := d.Run(report)
+
+		if !live {
+			if err := output.Render(os.Stdout, opts, doctorColumns, results); err != nil {
+				return err
+			}
+		}
+		failed := 0
+		for _, r := range results {
+			if r.Status == doctor.Fail {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d checks failed", failed, len(results))
+		}
+		return nil
+	},
+}
+
+var doctorColumns = []output.Column[doctor.Result]{
+	{Name: "check", Value: func(r doctor.Result) any { return r.Check }},
+	{Name: "status", Value: func(r doctor.Result) any { return r.Status }},
+	{Name: "detail", Value: func(r doctor.Result) any { return r.Detail }},
+	{Name: "hint", Value: func(r doctor.Result) any { return r.Hint }},
+	{Name: "duration_ms", Value: func(r doctor.Result) any { return r.DurationMS }},
+}
+
+func init() {
+	doctorCmd.Flags().String("canary", "", "UNIQUECODE of the test answer used for the PDF round trip")
+	doctorCmd.Flags().Bool("skip-pdf", false, "Skip the answer PDF round trip")
+	addOutputFlags(doctorCmd)
+}
@@ -0,0 +1,130 @@
+package cmd
+
+import (
+	"eusurveymgr/analysis"
+	"eusurveymgr/client"
+	"eusurveymgr/gdpr"
+	"eusurveymgr/log"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var gdprCmd = &cobra.Command{
+	Use:   "gdpr",
+	Short: "GDPR data subject requests",
+	Long:  "Collect the personal data held about a data subject across all surveys.",
+}
+
+var gdprExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Build a subject-access bundle for one person",
+	Long: `Find every answer set submitted with the given email address across all
+surveys and write a zip containing:
+
+  index.txt   human-readable overview with every question and answer
+  data.json   the same data in machine-readable form
+  pdfs/       the answer PDF of each submission (generated if needed)
+
+Answer PDFs use the same session-auth flow as 'pdf answer'. A PDF that cannot
+be generated is recorded in the index instead of failing the export.`,
+	Example: `  eusurveymgr gdpr export --email user@example.com
+  eusurveymgr gdpr export --email user@example.com --output sar-user.zip
+  eusurveymgr gdpr export --email user@example.com --no-pdf`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		email, _ := cmd.Flags().GetString("email")
+		outFile, _ := cmd.Flags().GetString("output")
+		noPDF, _ := cmd.Flags().GetBool("no-pdf")
+		if pseudonymize {
+			return fmt.Errorf("--pseudonymize cannot be used with gdpr export: the bundle is the subject's own data")
+		}
+
+		repo, err := openRepository()
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+
+		sets, err := repo.FindAnswerSetsByEmail(email)
+		if err != nil {
+			return err
+		}
+		if len(sets) == 0 {
+			return fmt.Errorf("no answer sets found for email=%q", email)
+		}
+		log.Infof("Found %d answer sets for %s", len(sets), email)
+
+		bundle := &gdpr.Bundle{Email: email, Generated: time.Now()}
+		var c *client.Client
+		if !noPDF {
+			c = newClient()
+		}
+
+		for _, s := range sets {
+			a := gdpr.AnswerSet{
+				SurveyID:    s.SurveyID,
+				SurveyAlias: s.Alias,
+				SurveyTitle: analysis.PlainText(s.Title),
+				AnswerSetID: s.AnswerSetID,
+				UniqueCode:  s.UniqueCode,
+				Date:        s.Date.String,
+			}
+
+			responses, err := repo.GetResponses(s.AnswerSetID)
+			if err != nil {
+				return err
+			}
+			for _, r := range responses {
+				a.Responses = append(a.Responses, gdpr.Response{
+					PA_ID:    r.PA_ID,
+					Question: analysis.PlainText(r.Question.String),
+					Value:    r.Value.String,
+				})
+			}
+
+			if c != nil {
+				data, err := c.GetAnswerPDF(s.UniqueCode, cfg.TimeoutSeconds)
+				if err != nil {
+					log.Warnf("No PDF for ANSWER_SET_ID=%d: %v", s.AnswerSetID, err)
+					a.PDFError = err.Error()
+				} else {
+					a.SetPDF(data)
+				}
+			}
+			bundle.AnswerSets = append(bundle.AnswerSets, a)
+		}
+
+		output := outFile
+		if output == "" {
+			name := strings.NewReplacer("@", "_at_", "/", "_").Replace(email)
+			output = filepath.Join(cfg.OutputDir, fmt.Sprintf("gdpr-%s-%s.zip", name, time.Now().Format("20060102")))
+		}
+		f, err := os.OpenFile(output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return fmt.Errorf("creating bundle: %w", err)
+		}
+		if err := gdpr.WriteZip(f, bundle); err != nil {
+			f.Close()
+			return fmt.Errorf("writing bundle: %w", err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("writing bundle: %w", err)
+		}
+
+		log.Infof("Subject-access bundle saved to %s (%d answer sets)", output, len(bundle.AnswerSets))
+		return nil
+	},
+}
+
+func init() {
+	gdprExportCmd.Flags().String("email", "", "Data subject email address")
+	gdprExportCmd.Flags().String("output", "", "Output zip (default: <output_dir>/gdpr-<email>-<date>.zip)")
+	gdprExportCmd.Flags().Bool("no-pdf", false, "Skip answer PDFs (no web login needed)")
+	gdprExportCmd.MarkFlagRequired("email")
+
+	gdprCmd.AddCommand(gdprExportCmd)
+}
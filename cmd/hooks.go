@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"context"
+	"eusurveymgr/config"
+	"eusurveymgr/hooks"
+	"eusurveymgr/output"
+	"eusurveymgr/watch"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Fire webhooks and commands on new responses",
+	Long: `Deliver new answer sets to the handlers listed under "hooks" in the config:
+an HTTP POST of a JSON payload, or a local command with EUSURVEY_* environment
+variables and the same JSON on stdin.
+
+Each answer set is delivered to each hook once. Delivery state (position,
+pending retries, failures) is kept in <state_dir>/hooks-state.json; every
+cycle and every retry locks it, so several runs may share the state dir.`,
+}
+
+var hooksRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Poll for new answer sets and deliver them to the hooks",
+	Long: `Poll the surveys of all configured hooks every --interval and deliver each
+new answer set. A hook seen for the first time starts after the newest
+existing answer set, unless --backfill is given.
+
+Failed deliveries are retried with exponential backoff (30s, doubling up to
+1h) until max_attempts (default 5), then kept as failed; 'hooks retry'
+queues them again. With --once, a single cycle is run (for cron).`,
+	Example: `  eusurveymgr hooks run
+  eusurveymgr hooks run --interval 1m
+  eusurveymgr hooks run --once`,
+	Annotations: map[string]string{"daemon": "unless --once"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		interval, _ := cmd.Flags().GetDuration("interval")
+		once, _ := cmd.Flags().GetBool("once")
+		backfill, _ := cmd.Flags().GetBool("backfill")
+		if len(cfg.Hooks) == 0 {
+			return fmt.Errorf("no hooks configured")
+		}
+		if interval <= 0 {
+			return fmt.Errorf("--interval must be positive")
+		}
+		p, err := identityPseudonymizer()
+		if err != nil {
+			return err
+		}
+
+		repo, err := openRepository()
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+
+		d, err := hooks.NewDispatcher(repo, cfg.Hooks, cfg.StateDir)
+		if err != nil {
+			return err
+		}
+		d.Backfill = backfill
+		if p != nil {
+			d.Transform = func(e *watch.Event) {
+				e.Name = p.Apply(e.Name)
+				e.Email = p.Apply(e.Email)
+			}
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if once {
+			return d.Tick(ctx)
+		}
+		return d.Run(ctx, interval)
+	},
+}
+
+// hookStatus is a configured hook with its delivery state, if the state
+// belongs to the hook's current survey.
+type hookStatus struct {
+	config.Hook
+	State *hooks.HookState
+}
+
+var hookStatusColumns = []output.Column[hookStatus]{
+	{Name: "hook", Value: func(h hookStatus) any { return h.Name }},
+	{Name: "survey", Value: func(h hookStatus) any { return h.Survey }},
+	{Name: "target", Value: func(h hookStatus) any {
+		if h.URL == "" && len(h.Command) > 0 {
+			return h.Command[0]
+		}
+		return h.URL
+	}},
+	{Name: "last_id", Value: func(h hookStatus) any { return h.state().LastID }},
+	{Name: "delivered", Value: func(h hookStatus) any { return h.state().Delivered }},
+	{Name: "pending", Value: func(h hookStatus) any { return len(h.state().Pending) }},
+	{Name: "failed", Value: func(h hookStatus) any { return len(h.state().Failed) }},
+	{Name: "pending_deliveries", Hidden: true, Value: func(h hookStatus) any { return h.state().Pending }},
+	{Name: "failed_deliveries", Hidden: true, Value: func(h hookStatus) any { return h.state().Failed }},
+}
+
+func (h hookStatus) state() *hooks.HookState {
+	if h.State == nil {
+		return &hooks.HookState{}
+	}
+	return h.State
+}
+
+var hooksStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show configured hooks and their delivery state",
+	Long: `Show each configured hook with its position and delivery counts. The table
+is followed by one line per failed delivery and per delivery waiting for a
+retry; the structured formats include them as pending_deliveries and
+failed_deliveries.`,
+	Example: `  eusurveymgr hooks status
+  eusurveymgr hooks status --format yaml`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+		state, err := hooks.LoadState(cfg.StateDir)
+		if err != nil {
+			return err
+		}
+
+		var status []hookStatus
+		for _, h := range cfg.Hooks {
+			s := hookStatus{Hook: h}
+			if hs := state.Hooks[h.Name]; hs != nil && hs.Survey == h.Survey {
+				s.State = hs
+			}
+			status = append(status, s)
+		}
+		if err := output.Render(os.Stdout, opts, hookStatusColumns, status); err != nil {
+			return err
+		}
+		if opts.Format != output.Table {
+			return nil
+		}
+
+		for _, s := range status {
+			for _, del := range s.state().Failed {
+				fmt.Printf("FAILED %s ANSWER_SET_ID=%d attempts=%d: %s\n",
+					s.Name, del.Event.AnswerSetID, del.Attempts, del.LastError)
+			}
+			for _, del := range s.state().Pending {
+				if del.Attempts > 0 {
+					fmt.Printf("RETRY  %s ANSWER_SET_ID=%d attempts=%d next=%s: %s\n",
+						s.Name, del.Event.AnswerSetID, del.Attempts, del.NextAttempt.Format(time.DateTime), del.LastError)
+				}
+			}
+		}
+		return nil
+	},
+}
+
+var hooksRetryCmd = &cobra.Command{
+	Use:     "retry",
+	Short:   "Queue the failed deliveries of a hook again",
+	Example: "  eusurveymgr hooks retry --hook crm",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		name, _ := cmd.Flags().GetString("hook")
+
+		// Retry only touches the state file; no repository is needed.
+		d, err := hooks.NewDispatcher(nil, cfg.Hooks, cfg.StateDir)
+		if err != nil {
+			return err
+		}
+		n, err := d.Retry(name)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Queued %d failed deliveries of %s; they are sent on the next 'hooks run' cycle.\n", n, name)
+		return nil
+	},
+}
+
+func init() {
+	hooksRunCmd.Flags().Duration("interval", 30*time.Second, "Polling interval")
+	hooksRunCmd.Flags().Bool("once", false, "Run a single poll/deliver cycle and exit")
+	hooksRunCmd.Flags().Bool("backfill", false, "Deliver existing answer sets to hooks that have no state yet")
+
+	addOutputFlags(hooksStatusCmd)
+
+	hooksRetryCmd.Flags().String("hook", "", "Hook name")
+	hooksRetryCmd.MarkFlagRequired("hook")
+
+	hooksCmd.AddCommand(hooksRunCmd)
+	hooksCmd.AddCommand(hooksStatusCmd)
+	hooksCmd.AddCommand(hooksRetryCmd)
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"eusurveymgr/mockserver"
+	"net/http"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var mockServerCmd = &cobra.Command{
+	Use:   "mock-server",
+	Short: "Run a local EUSurvey stand-in for offline testing",
+	Long: `Serve the EUSurvey endpoints used by eusurveymgr from fixture data:
+
+  /auth/login, /login                   CSRF form login (302 on success and failure)
+  /webservice/getMySurveys              Basic Auth survey list
+  /webservice/getSurveyMetadata/{alias}
+  /webservice/getSurveyPDF/{alias}
+  /webservice/prepareResults/{id}/{ids} 201 + task ID
+  /webservice/getResults/{task}         204 while exporting, then 200 (412 if no results)
+  /worker/createanswerpdf/{code}        "OK"
+  /pdf/answerready/{code}               "exists" once generated
+  /pdf/answer/{code}                    answer PDF
+  /preparecontribution/{code}           answer HTML (with /resources/ CSS and image)
+
+The fixture uses the same JSON format as --source fixtures; without --fixture
+a built-in demo survey is served. Point base_url at the listen address and
+use the same --user/--password as web_user/web_password. No config file is
+needed to run the server.`,
+	Example: `  eusurveymgr mock-server
+  eusurveymgr mock-server --listen 127.0.0.1:8089 --fixture surveys.json --export-delay 10s --pdf-delay 3s
+  eusurveymgr mock-server --fail createanswerpdf --fail-rate 0.1 --max-requests-per-day 100`,
+	Annotations: map[string]string{"config": "none", "daemon": "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		fixturePath, _ := cmd.Flags().GetString("fixture")
+		var opts mockserver.Options
+		opts.Username, _ = cmd.Flags().GetString("user")
+		opts.Password, _ = cmd.Flags().GetString("password")
+		opts.ExportDelay, _ = cmd.Flags().GetDuration("export-delay")
+		opts.PDFDelay, _ = cmd.Flags().GetDuration("pdf-delay")
+		opts.Latency, _ = cmd.Flags().GetDuration("latency")
+		opts.FailureRate, _ = cmd.Flags().GetFloat64("fail-rate")
+		opts.FailEndpoints, _ = cmd.Flags().GetStringSlice("fail")
+		opts.MaxRequestsPerDay, _ = cmd.Flags().GetInt("max-requests-per-day")
+		opts.SessionTTL, _ = cmd.Flags().GetDuration("session-ttl")
+
+		fixture := mockserver.DemoFixture()
+		if fixturePath != "" {
+			var err error
+			if fixture, err = db.LoadFixture(fixturePath); err != nil {
+				return err
+			}
+		}
+
+		log.Infof("Mock EUSurvey listening on http://%s (user %q)", listen, opts.Username)
+		return http.ListenAndServe(listen, mockserver.New(fixture, opts))
+	},
+}
+
+func init() {
+	mockServerCmd.Flags().String("listen", "127.0.0.1:8089", "Listen address")
+	mockServerCmd.Flags().String("fixture", "", "Fixture JSON (default: built-in demo survey)")
+	mockServerCmd.Flags().String("user", "root", "Accepted web user")
+	mockServerCmd.Flags().String("password", "secret", "Accepted web password")
+	mockServerCmd.Flags().Duration("export-delay", 3*time.Second, "Time getResults answers 204 before completing")
+	mockServerCmd.Flags().Duration("pdf-delay", 2*time.Second, "Time until a triggered answer PDF is ready")
+	mockServerCmd.Flags().Duration("latency", 0, "Delay added to every request")
+	mockServerCmd.Flags().Float64("fail-rate", 0, "Probability (0-1) that a request fails with HTTP 500")
+	mockServerCmd.Flags().StringSlice("fail", nil, "Endpoints that always fail (e.g. createanswerpdf,getResults,login)")
+	mockServerCmd.Flags().Int("max-requests-per-day", 0, "Webservice daily request limit (0 = unlimited)")
+	mockServerCmd.Flags().Duration("session-ttl", 0, "Time after which a login session expires (0 = never)")
+}
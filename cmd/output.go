@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"eusurveymgr/output"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+// addOutputFlags registers the shared output flags of list and detail
+// commands. --json stays as a shorthand for --format json; commands that
+// had --csv or --ndjson keep those too and outputOptions honours them.
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().String("format", "table", "Output format: table, json, ndjson, csv, yaml or template")
+	cmd.Flags().StringSlice("columns", nil, "Columns to show, in this order (comma-separated)")
+	cmd.Flags().Bool("no-headers", false, "Omit the header row of table and CSV output")
+	cmd.Flags().String("template", "", "Go text/template executed per record (implies --format template)")
+	cmd.Flags().Bool("json", false, "Shorthand for --format json")
+}
+
+// outputOptions reads the flags registered by addOutputFlags.
+func outputOptions(cmd *cobra.Command) (output.Options, error) {
+	name, _ := cmd.Flags().GetString("format")
+	columns, _ := cmd.Flags().GetStringSlice("columns")
+	noHeaders, _ := cmd.Flags().GetBool("no-headers")
+	tmpl, _ := cmd.Flags().GetString("template")
+
+	format, err := output.ParseFormat(name)
+	if err != nil {
+		return output.Options{}, err
+	}
+	explicit := cmd.Flags().Changed("format")
+	alias := func(f output.Format) error {
+		if explicit && format != f {
+			return fmt.Errorf("--%s conflicts with --format %s", f, format)
+		}
+		format, explicit = f, true
+		return nil
+	}
+	for _, f := range []output.Format{output.JSON, output.CSV, output.NDJSON} {
+		if set, _ := cmd.Flags().GetBool(string(f)); set {
+			if err := alias(f); err != nil {
+				return output.Options{}, err
+			}
+		}
+	}
+	if tmpl != "" {
+		if err := alias(output.Template); err != nil {
+			return output.Options{}, err
+		}
+	}
+	return output.Options{Format: format, Columns: columns, NoHeaders: noHeaders, Template: tmpl}, nil
+}
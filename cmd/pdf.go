@@ -1,13 +1,13 @@
 package cmd
 
 import (
-	"eusurveymgr/client"
+	"bytes"
+	"eusurveymgr/answerpdf"
 	"eusurveymgr/db"
 	"eusurveymgr/log"
+	"eusurveymgr/pseudo"
 	"fmt"
 	"os"
-	"path/filepath"
-	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -27,7 +27,7 @@ var pdfSurveyCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		alias, _ := cmd.Flags().GetString("alias")
 		outFile, _ := cmd.Flags().GetString("output")
-		c := client.New(cfg)
+		c := newClient()
 
 		log.Infof("Downloading survey PDF for %s...", alias)
 		data, err := c.GetSurveyPDF(alias)
@@ -55,115 +55,122 @@ var pdfAnswerCmd = &cobra.Command{
 
 Provide either --code for a known UNIQUECODE, or --email and --survey
 to look up the code from the database. Skips generation if the PDF
-already exists on the server.`,
+already exists on the server. With --html-fallback, a failed PDF generation
+saves the rendered answer as self-contained HTML instead, like 'answer html'.
+
+With --local, the PDF is rendered here from the database (--source works
+too): the survey's elements with the respondent's answers, chosen options by
+their label. No web login is needed. pdf_header, pdf_logo and pdf_font set
+the page header, logo and font. Only --local PDFs can be pseudonymised:
+without it, --pseudonymize is rejected.`,
 	Example: `  eusurveymgr pdf answer --code ae8d5fec-daaf-4aba-b860-544d1f717d8a
   eusurveymgr pdf answer --email user@example.com --survey 4578
-  eusurveymgr pdf answer --email user@example.com --survey 4578 --output ./pdfs`,
+  eusurveymgr pdf answer --email user@example.com --survey 4578 --output ./pdfs
+  eusurveymgr pdf answer --code ae8d5fec-daaf-4aba-b860-544d1f717d8a --html-fallback
+  eusurveymgr --source survey-4609.sqlite pdf answer --email user@example.com --survey 4609 --local`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		code, _ := cmd.Flags().GetString("code")
-		email, _ := cmd.Flags().GetString("email")
-		surveyID, _ := cmd.Flags().GetInt64("survey")
 		outDir, _ := cmd.Flags().GetString("output")
-		c := client.New(cfg)
+		htmlFallback, _ := cmd.Flags().GetBool("html-fallback")
+		local, _ := cmd.Flags().GetBool("local")
+		if !local {
+			if err := rejectPseudonymize("pdf answer without --local"); err != nil {
+				return err
+			}
+		}
+		p, err := identityPseudonymizer()
+		if err != nil {
+			return err
+		}
 
-		var uniqueCode string
-		var answerSetID int64
-		var emailAddr string
+		if local {
+			repo, err := openRepository()
+			if err != nil {
+				return err
+			}
+			defer repo.Close()
 
-		if code != "" {
-			uniqueCode = code
-		} else if email != "" {
-			if surveyID == 0 {
-				return fmt.Errorf("--survey is required when using --email")
+			t, err := lookupAnswer(cmd, repo)
+			if err != nil {
+				return err
 			}
-			dbconn, err := db.ConnectToMySQL(cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName)
+			data, err := renderAnswerPDF(repo, t.UniqueCode, p)
 			if err != nil {
-				return fmt.Errorf("connecting to DB for UNIQUECODE lookup: %w", err)
+				return err
 			}
-			defer dbconn.Close()
-
-			emailAddr = email
-			answerSetID, uniqueCode, err = db.LookupUniqueCode(dbconn, email, surveyID)
+			outPath, err := t.save(outDir, "pdf", p, data)
 			if err != nil {
 				return err
 			}
-			log.Infof("Found ANSWER_SET_ID=%d UNIQUECODE=%s", answerSetID, uniqueCode)
-		} else {
-			return fmt.Errorf("provide either --code or --email (with --survey)")
+			log.With("code", t.UniqueCode).Infof("Answer PDF rendered to %s (%d bytes)", outPath, len(data))
+			return nil
 		}
 
-		// Check if PDF already exists before triggering generation
-		ready, err := c.IsAnswerPDFReady(uniqueCode)
+		c := newClient()
+		t, err := lookupAnswer(cmd, nil)
 		if err != nil {
-			return fmt.Errorf("checking PDF readiness: %w", err)
+			return err
 		}
 
-		if !ready {
-			log.Infof("Triggering PDF generation for %s...", uniqueCode)
-			if err := c.CreateAnswerPDF(uniqueCode); err != nil {
+		data, err := c.GetAnswerPDF(t.UniqueCode, cfg.TimeoutSeconds)
+		if err != nil {
+			if !htmlFallback {
 				return err
 			}
-
-			log.Infof("Waiting for PDF to be ready...")
-			deadline := time.Now().Add(time.Duration(cfg.TimeoutSeconds) * time.Second)
-			delay := time.Second
-			for {
-				ready, err = c.IsAnswerPDFReady(uniqueCode)
-				if err != nil {
-					return fmt.Errorf("checking PDF readiness: %w", err)
-				}
-				if ready {
-					break
-				}
-				if time.Now().After(deadline) {
-					return fmt.Errorf("PDF generation timed out after %ds", cfg.TimeoutSeconds)
-				}
-				log.Debugf("PDF not ready yet, retrying in %v...", delay)
-				time.Sleep(delay)
-				if delay < 5*time.Second {
-					delay += time.Second
-				}
+			log.With("code", t.UniqueCode).Warnf("PDF generation failed, saving the answer HTML instead: %v", err)
+			html, herr := c.GetAnswerHTML(t.UniqueCode)
+			if herr != nil {
+				return fmt.Errorf("%w (HTML fallback: %v)", err, herr)
+			}
+			outPath, err := t.save(outDir, "html", p, html)
+			if err != nil {
+				return err
 			}
-		} else {
-			log.Infof("PDF already exists for %s", uniqueCode)
+			log.With("code", t.UniqueCode).Infof("Answer HTML saved to %s (%d bytes)", outPath, len(html))
+			return nil
 		}
 
-		log.Infof("Downloading PDF...")
-		data, err := c.DownloadAnswerPDF(uniqueCode)
+		outPath, err := t.save(outDir, "pdf", p, data)
 		if err != nil {
 			return err
 		}
-
-		if err := os.MkdirAll(outDir, 0755); err != nil {
-			return fmt.Errorf("creating output directory: %w", err)
-		}
-
-		var filename string
-		if emailAddr != "" {
-			filename = fmt.Sprintf("%d--%s.pdf", answerSetID, emailAddr)
-		} else {
-			filename = uniqueCode + ".pdf"
-		}
-		outPath := filepath.Join(outDir, filename)
-
-		if err := os.WriteFile(outPath, data, 0644); err != nil {
-			return fmt.Errorf("writing PDF: %w", err)
-		}
-
-		log.Infof("Answer PDF saved to %s (%d bytes)", outPath, len(data))
+		log.With("code", t.UniqueCode).Infof("Answer PDF saved to %s (%d bytes)", outPath, len(data))
 		return nil
 	},
 }
 
+// renderAnswerPDF renders the answer set uniqueCode from repo with the
+// pdf_* layout settings, pseudonymising identities with p.
+func renderAnswerPDF(repo db.SurveyRepository, uniqueCode string, p *pseudo.Pseudonymizer) ([]byte, error) {
+	set, err := repo.GetAnswerSetByCode(uniqueCode)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := repo.GetResponses(set.AnswerSetID)
+	if err != nil {
+		return nil, err
+	}
+	pseudonymizeResponses(p, responses)
+	doc, err := answerpdf.Load(repo, set, responses)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	opts := answerpdf.Options{Header: cfg.PDFHeader, Logo: cfg.PDFLogo, Font: cfg.PDFFont}
+	if err := answerpdf.Render(&buf, doc, opts); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
 func init() {
 	pdfSurveyCmd.Flags().String("alias", "", "Survey alias/shortname")
 	pdfSurveyCmd.Flags().String("output", "", "Output file (default: <alias>.pdf)")
 	pdfSurveyCmd.MarkFlagRequired("alias")
 
-	pdfAnswerCmd.Flags().String("code", "", "Answer UNIQUECODE")
-	pdfAnswerCmd.Flags().String("email", "", "Respondent email address")
-	pdfAnswerCmd.Flags().Int64("survey", 0, "Survey ID (required with --email)")
-	pdfAnswerCmd.Flags().String("output", ".", "Output directory")
+	addAnswerFlags(pdfAnswerCmd)
+	pdfAnswerCmd.Flags().Bool("html-fallback", false, "Save the answer HTML (see 'answer html') if PDF generation fails")
+	pdfAnswerCmd.Flags().Bool("local", false, "Render the PDF from the database instead of the server's PDF worker")
+	pdfAnswerCmd.MarkFlagsMutuallyExclusive("local", "html-fallback")
 
 	pdfCmd.AddCommand(pdfSurveyCmd)
 	pdfCmd.AddCommand(pdfAnswerCmd)
@@ -0,0 +1,306 @@
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"eusurveymgr/analysis"
+	"eusurveymgr/answerpdf"
+	"eusurveymgr/client"
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"eusurveymgr/pseudo"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// bundleEntry is one respondent of a bundle: an answer set of the survey,
+// a PDF file, or both.
+type bundleEntry struct {
+	set  *db.AnswerSetRow
+	file string
+}
+
+// answerFileRe matches the names written by 'pdf answer':
+// <ANSWER_SET_ID>--<email>.pdf or <UNIQUECODE>.pdf.
+var answerFileRe = regexp.MustCompile(`^(?:(\d+)--.*|([0-9A-Za-z-]+))\.pdf$`)
+
+// matchAnswerFile returns the answer set a 'pdf answer' file belongs to.
+func matchAnswerFile(sets []db.AnswerSetRow, file string) *db.AnswerSetRow {
+	m := answerFileRe.FindStringSubmatch(filepath.Base(file))
+	if m == nil {
+		return nil
+	}
+	for i, s := range sets {
+		if (m[1] != "" && strconv.FormatInt(s.AnswerSetID, 10) == m[1]) || (m[2] != "" && s.UniqueCode == m[2]) {
+			return &sets[i]
+		}
+	}
+	return nil
+}
+
+// matchRespondent returns the answer set with UNIQUECODE or email ref.
+func matchRespondent(sets []db.AnswerSetRow, ref string) *db.AnswerSetRow {
+	for i, s := range sets {
+		if s.UniqueCode == ref || (s.Email.Valid && strings.EqualFold(s.Email.String, ref)) {
+			return &sets[i]
+		}
+	}
+	return nil
+}
+
+// readRoster returns the non-empty lines of a roster file, without
+// # comments.
+func readRoster(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading roster: %w", err)
+	}
+	defer f.Close()
+	var refs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			refs = append(refs, line)
+		}
+	}
+	return refs, scanner.Err()
+}
+
+// title names e in the outline, index and cover page: the respondent's
+// name, else email (pseudonymised by p), else the file name.
+func (e bundleEntry) title(p *pseudo.Pseudonymizer) string {
+	switch {
+	case e.set == nil:
+		return strings.TrimSuffix(filepath.Base(e.file), filepath.Ext(e.file))
+	case e.set.Name.Valid && e.set.Name.String != "":
+		return p.Apply(e.set.Name.String)
+	case e.set.Email.Valid && e.set.Email.String != "":
+		return p.Apply(e.set.Email.String)
+	}
+	return e.set.UniqueCode
+}
+
+// details are the cover page lines of e: email, date and UNIQUECODE.
+func (e bundleEntry) details(p *pseudo.Pseudonymizer) []string {
+	if e.set == nil {
+		return nil
+	}
+	var details []string
+	if e.set.Email.Valid && e.set.Email.String != "" {
+		details = append(details, p.Apply(e.set.Email.String))
+	}
+	if e.set.Date.Valid {
+		details = append(details, "Submitted "+e.set.Date.String)
+	}
+	return append(details, e.set.UniqueCode)
+}
+
+// date is the submission date of e, empty if unknown.
+func (e bundleEntry) date() string {
+	if e.set == nil {
+		return ""
+	}
+	return e.set.Date.String
+}
+
+var pdfBundleCmd = &cobra.Command{
+	Use:   "bundle [file.pdf ...]",
+	Short: "Merge answer PDFs into one bookmarked PDF",
+	Long: `Merge the answer PDFs of many respondents into one PDF, e.g. one file per
+class, with an outline (bookmark) entry per respondent.
+
+The PDFs are either given as files (as written by 'pdf answer'), or fetched
+for the answer sets of --survey: all of them, or those selected by --code,
+--email or a --roster file with one UNIQUECODE or email per line. Fetched
+PDFs come from the server like 'pdf answer', or with --local are rendered
+from the database. With --survey, files named <ANSWER_SET_ID>--<email>.pdf
+or <UNIQUECODE>.pdf are matched to their answer sets, so they get the
+respondent's name and date too.
+
+--sort orders the respondents by name (Romanian collation), date, or
+roster (the order of the files, --code/--email or the roster). --index adds
+an index page with the first page of each respondent, --cover a cover page
+before each respondent. A respondent whose PDF cannot be fetched is skipped
+with a warning. --pseudonymize needs --local: PDFs rendered by EUSurvey, or
+given as files, cannot be pseudonymised.`,
+	Example: `  eusurveymgr pdf bundle --survey 4609 --roster class-9b.txt --sort roster --index --output 9b.pdf
+  eusurveymgr pdf bundle --survey 4609 --local --cover --index
+  eusurveymgr --source survey-4609.sqlite pdf bundle --survey 4609 pdfs/*.pdf --sort date
+  eusurveymgr pdf bundle a.pdf b.pdf --output both.pdf`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		surveyID, _ := cmd.Flags().GetInt64("survey")
+		codes, _ := cmd.Flags().GetStringArray("code")
+		emails, _ := cmd.Flags().GetStringArray("email")
+		rosterFile, _ := cmd.Flags().GetString("roster")
+		local, _ := cmd.Flags().GetBool("local")
+		sortBy, _ := cmd.Flags().GetString("sort")
+		opts := answerpdf.BundleOptions{Font: cfg.PDFFont}
+		opts.Title, _ = cmd.Flags().GetString("title")
+		opts.Index, _ = cmd.Flags().GetBool("index")
+		opts.Cover, _ = cmd.Flags().GetBool("cover")
+		outFile, _ := cmd.Flags().GetString("output")
+
+		switch sortBy {
+		case "name", "date", "roster":
+		default:
+			return fmt.Errorf("--sort must be name, date or roster, got %q", sortBy)
+		}
+		refs := append(codes, emails...)
+		if rosterFile != "" {
+			roster, err := readRoster(rosterFile)
+			if err != nil {
+				return err
+			}
+			refs = append(refs, roster...)
+		}
+		if surveyID == 0 && (len(args) == 0 || len(refs) > 0 || local) {
+			return fmt.Errorf("--survey is required unless only PDF files are given")
+		}
+		if len(args) > 0 && len(refs) > 0 {
+			return fmt.Errorf("give either PDF files or --code/--email/--roster, not both")
+		}
+		if sortBy == "date" && surveyID == 0 {
+			return fmt.Errorf("--sort date needs --survey")
+		}
+		if len(args) > 0 && pseudonymize {
+			return fmt.Errorf("--pseudonymize is not supported for pdf bundle of PDF files; use --survey with --local")
+		}
+		if !local {
+			if err := rejectPseudonymize("pdf bundle without --local"); err != nil {
+				return err
+			}
+		}
+		p, err := identityPseudonymizer()
+		if err != nil {
+			return err
+		}
+
+		var repo db.SurveyRepository
+		var sets []db.AnswerSetRow
+		if surveyID != 0 {
+			if repo, err = openRepository(); err != nil {
+				return err
+			}
+			defer repo.Close()
+			if sets, err = repo.ListAnswerSets(surveyID); err != nil {
+				return err
+			}
+			if opts.Title == "" {
+				surveys, err := repo.ListSurveys()
+				if err != nil {
+					return err
+				}
+				for _, s := range surveys {
+					if s.SurveyID == surveyID {
+						opts.Title = analysis.PlainText(s.Title)
+					}
+				}
+			}
+		}
+
+		var entries []bundleEntry
+		switch {
+		case len(args) > 0:
+			for _, file := range args {
+				e := bundleEntry{file: file, set: matchAnswerFile(sets, file)}
+				if e.set == nil && surveyID != 0 {
+					log.Warnf("%s: no answer set of survey %d matches the file name", file, surveyID)
+				}
+				entries = append(entries, e)
+			}
+		case len(refs) > 0:
+			for _, ref := range refs {
+				set := matchRespondent(sets, ref)
+				if set == nil {
+					log.Warnf("%s: no answer set in survey %d, skipped", ref, surveyID)
+					continue
+				}
+				entries = append(entries, bundleEntry{set: set})
+			}
+		default:
+			for i := range sets {
+				entries = append(entries, bundleEntry{set: &sets[i]})
+			}
+		}
+
+		switch sortBy {
+		case "name":
+			c := collate.New(language.Romanian, collate.IgnoreCase)
+			slices.SortStableFunc(entries, func(a, b bundleEntry) int {
+				return c.CompareString(a.title(p), b.title(p))
+			})
+		case "date":
+			slices.SortStableFunc(entries, func(a, b bundleEntry) int {
+				return strings.Compare(a.date(), b.date())
+			})
+		}
+
+		var c *client.Client
+		var parts []answerpdf.Part
+		for _, e := range entries {
+			var data []byte
+			var err error
+			switch {
+			case e.file != "":
+				data, err = os.ReadFile(e.file)
+			case local:
+				data, err = renderAnswerPDF(repo, e.set.UniqueCode, p)
+			default:
+				if c == nil {
+					c = newClient()
+				}
+				data, err = c.GetAnswerPDF(e.set.UniqueCode, cfg.TimeoutSeconds)
+			}
+			if err != nil {
+				log.Warnf("No PDF for %s, skipped: %v", e.title(p), err)
+				continue
+			}
+			parts = append(parts, answerpdf.Part{Title: e.title(p), Details: e.details(p), PDF: data})
+		}
+		if opts.Title == "" {
+			opts.Title = "Answers"
+		}
+
+		var buf bytes.Buffer
+		if err := answerpdf.Bundle(&buf, parts, opts); err != nil {
+			return err
+		}
+		if outFile == "" {
+			name := "bundle.pdf"
+			if surveyID != 0 {
+				name = fmt.Sprintf("bundle-%d.pdf", surveyID)
+			}
+			outFile = filepath.Join(cfg.OutputDir, name)
+		}
+		if err := os.WriteFile(outFile, buf.Bytes(), 0644); err != nil {
+			return fmt.Errorf("writing PDF: %w", err)
+		}
+		log.Infof("Bundle of %d of %d respondents saved to %s (%d bytes)", len(parts), len(entries), outFile, buf.Len())
+		return nil
+	},
+}
+
+func init() {
+	pdfBundleCmd.Flags().Int64("survey", 0, "Survey ID whose answer sets are bundled")
+	pdfBundleCmd.Flags().StringArray("code", nil, "Answer UNIQUECODE (repeatable)")
+	pdfBundleCmd.Flags().StringArray("email", nil, "Respondent email address (repeatable)")
+	pdfBundleCmd.Flags().String("roster", "", "File with one UNIQUECODE or email per line")
+	pdfBundleCmd.Flags().Bool("local", false, "Render the PDFs from the database (see 'pdf answer --local')")
+	pdfBundleCmd.Flags().String("sort", "name", "Order: name, date or roster")
+	pdfBundleCmd.Flags().Bool("index", false, "Add an index page listing the respondents")
+	pdfBundleCmd.Flags().Bool("cover", false, "Add a cover page before each respondent")
+	pdfBundleCmd.Flags().String("title", "", "Title of the index and cover pages (default: survey title)")
+	pdfBundleCmd.Flags().String("output", "", "Output file (default: <output_dir>/bundle-<survey>.pdf)")
+
+	pdfCmd.AddCommand(pdfBundleCmd)
+}
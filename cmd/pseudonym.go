@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"eusurveymgr/output"
+	"eusurveymgr/pseudo"
+	"eusurveymgr/watch"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// identityPseudonymizer returns the pseudonymizer selected by --pseudonymize,
+// or nil when identities are written as stored.
+func identityPseudonymizer() (*pseudo.Pseudonymizer, error) {
+	if !pseudonymize {
+		return nil, nil
+	}
+	return pseudo.New(cfg.PseudonymKey, cfg.PseudonymMode)
+}
+
+// rejectPseudonymize fails commands whose output EUSurvey renders, so that
+// --pseudonymize cannot reach the identities in it.
+func rejectPseudonymize(what string) error {
+	if pseudonymize {
+		return fmt.Errorf("--pseudonymize is not supported for %s (rendered by EUSurvey)", what)
+	}
+	return nil
+}
+
+// identityValues returns the name and email of an answer set's responses:
+// the first and last PA_ID=0 rows, matching the rule used by ListAnswerSets.
+func identityValues(responses []db.ResponseRow) map[string]bool {
+	var identity []string
+	for _, r := range responses {
+		if r.PA_ID == 0 && r.Value.Valid {
+			identity = append(identity, r.Value.String)
+		}
+	}
+	values := make(map[string]bool)
+	if len(identity) > 0 {
+		values[identity[0]] = true
+		values[identity[len(identity)-1]] = true
+	}
+	return values
+}
+
+// pseudonymizeResponses replaces the identity values of an answer set's
+// responses in place; a nil p leaves them as stored.
+func pseudonymizeResponses(p *pseudo.Pseudonymizer, responses []db.ResponseRow) {
+	if p == nil {
+		return
+	}
+	identity := identityValues(responses)
+	for i, r := range responses {
+		if r.PA_ID == 0 && identity[r.Value.String] {
+			responses[i].Value = p.NullString(r.Value)
+		}
+	}
+}
+
+var pseudonymCmd = &cobra.Command{
+	Use:   "pseudonym",
+	Short: "Pseudonym administration",
+	Long:  "Administer the keyed pseudonyms written by --pseudonymize.",
+}
+
+var pseudonymReidentifyCmd = &cobra.Command{
+	Use:   "reidentify",
+	Short: "Map a pseudonym back to the respondent (authorised staff only)",
+	Long: `Find the respondent(s) behind a pseudonym by recomputing the pseudonyms of
+every name and email in the database with the configured pseudonym_key.
+
+Only OS users listed in reidentify_users may run this command. Every attempt,
+successful or not, is appended to <output_dir>/reidentify-audit.log together
+with the stated --reason.
+
+reidentify_users guards against mistakes, it is not access control: it is
+read from the same config as pseudonym_key, and anyone who can read that key
+can recompute pseudonyms without this command. Keep pseudonym_key (e.g. via
+pseudonym_key_file or the secrets file) readable by authorised staff only.`,
+	Example: `  eusurveymgr pseudonym reidentify --pseudonym p-3f9a0c1d2e4b5a69 --survey 4609 --reason "ticket 1234"`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		pseudonym, _ := cmd.Flags().GetString("pseudonym")
+		surveyID, _ := cmd.Flags().GetInt64("survey")
+		reason, _ := cmd.Flags().GetString("reason")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		u, err := user.Current()
+		if err != nil {
+			return fmt.Errorf("determining current user: %w", err)
+		}
+		authorised := slices.Contains(cfg.ReidentifyUsers, u.Username)
+		matches := 0
+		defer func() {
+			auditReidentify(u.Username, pseudonym, reason, authorised, matches)
+		}()
+		if !authorised {
+			return fmt.Errorf("user %q is not listed in reidentify_users", u.Username)
+		}
+		if cfg.PseudonymMode == pseudo.ModeDrop {
+			return fmt.Errorf("pseudonym_mode %q drops identities; nothing can be re-identified", pseudo.ModeDrop)
+		}
+		p, err := pseudo.New(cfg.PseudonymKey, pseudo.ModeHash)
+		if err != nil {
+			return err
+		}
+
+		repo, err := openRepository()
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+
+		surveyIDs := []int64{surveyID}
+		if surveyID == 0 {
+			surveys, err := repo.ListSurveys()
+			if err != nil {
+				return err
+			}
+			surveyIDs = surveyIDs[:0]
+			for _, s := range surveys {
+				surveyIDs = append(surveyIDs, s.SurveyID)
+			}
+		}
+
+		var found []watch.Event
+		for _, id := range surveyIDs {
+			answers, err := repo.ListAnswerSets(id)
+			if err != nil {
+				return err
+			}
+			for _, a := range answers {
+				if p.Matches(a.Name.String, pseudonym) || p.Matches(a.Email.String, pseudonym) {
+					found = append(found, watch.NewEvent(id, a))
+				}
+			}
+		}
+		matches = len(found)
+		if matches == 0 {
+			return fmt.Errorf("no respondent matches pseudonym %q", pseudonym)
+		}
+		return output.Render(os.Stdout, opts, respondentColumns, found)
+	},
+}
+
+var respondentColumns = []output.Column[watch.Event]{
+	{Name: "survey_id", Value: func(e watch.Event) any { return e.SurveyID }},
+	{Name: "answer_set_id", Value: func(e watch.Event) any { return e.AnswerSetID }},
+	{Name: "uniquecode", Value: func(e watch.Event) any { return e.UniqueCode }},
+	{Name: "date", Hidden: true, Value: func(e watch.Event) any { return e.Date }},
+	{Name: "name", Value: func(e watch.Event) any { return e.Name }},
+	{Name: "email", Value: func(e watch.Event) any { return e.Email }},
+}
+
+// auditReidentify appends one line per re-identification attempt to the
+// audit log. Failures to write the log are reported but do not hide results.
+func auditReidentify(username, pseudonym, reason string, authorised bool, matches int) {
+	path := filepath.Join(cfg.OutputDir, "reidentify-audit.log")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		log.Errorf("Writing re-identification audit log: %v", err)
+		return
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "%s user=%q pseudonym=%q authorised=%v matches=%d reason=%q\n",
+		time.Now().Format(time.RFC3339), username, pseudonym, authorised, matches, reason)
+}
+
+func init() {
+	pseudonymReidentifyCmd.Flags().String("pseudonym", "", "Pseudonym to resolve (p-...)")
+	pseudonymReidentifyCmd.Flags().Int64("survey", 0, "Limit the search to one survey ID")
+	pseudonymReidentifyCmd.Flags().String("reason", "", "Reason for re-identification (recorded in the audit log)")
+	addOutputFlags(pseudonymReidentifyCmd)
+	pseudonymReidentifyCmd.MarkFlagRequired("pseudonym")
+	pseudonymReidentifyCmd.MarkFlagRequired("reason")
+
+	pseudonymCmd.AddCommand(pseudonymReidentifyCmd)
+}
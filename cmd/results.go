@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"bufio"
-	"eusurveymgr/client"
 	"eusurveymgr/log"
 	"fmt"
 	"os"
@@ -22,7 +21,8 @@ var resultsExportCmd = &cobra.Command{
 	Short: "Export survey results to XML",
 	Long: `Start an async results export on the server and poll until complete.
 Accepts both numeric survey IDs and aliases. Can be slow for large surveys
-as the server generates PDFs as a side-effect.`,
+as the server generates PDFs as a side-effect. The XML is written as
+EUSurvey exports it, so --pseudonymize is rejected.`,
 	Example: `  eusurveymgr results export --id Check4SkillsInRomana
   eusurveymgr results export --id 4578 --output results-ro.xml
   eusurveymgr results export --id Check4SkillsInEnglish --showids=false`,
@@ -31,6 +31,9 @@ as the server generates PDFs as a side-effect.`,
 		outFile, _ := cmd.Flags().GetString("output")
 		showIDs, _ := cmd.Flags().GetBool("showids")
 		yes, _ := cmd.Flags().GetBool("yes")
+		if err := rejectPseudonymize("results export"); err != nil {
+			return err
+		}
 
 		if !yes {
 			fmt.Fprintf(os.Stderr, "WARNING: This triggers a server-side export for survey %q that generates\n", formID)
@@ -45,7 +48,7 @@ as the server generates PDFs as a side-effect.`,
 			}
 		}
 
-		c := client.New(cfg)
+		c := newClient()
 
 		log.Infof("Preparing results export for survey %s...", formID)
 		taskID, err := c.PrepareResults(formID, showIDs)
@@ -67,7 +70,7 @@ as the server generates PDFs as a side-effect.`,
 			return fmt.Errorf("writing output file: %w", err)
 		}
 
-		log.Infof("Results saved to %s (%d bytes)", output, len(data))
+		log.With("survey", formID).Infof("Results saved to %s (%d bytes)", output, len(data))
 		return nil
 	},
 }
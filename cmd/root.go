@@ -1,42 +1,81 @@
 package cmd
 
 import (
+	"errors"
+	"eusurveymgr/client"
 	"eusurveymgr/config"
 	"eusurveymgr/log"
+	"eusurveymgr/metrics"
 	"fmt"
+	"io/fs"
+	"net/http"
 	"os"
+	"sync"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	cfgFile string
-	verbose bool
-	cfg     *config.Configuration
+	cfgFile      string
+	profile      string
+	verbose      bool
+	pseudonymize bool
+	dbSource     string
+	cfg          *config.Configuration
+
+	metricsListen   string
+	metricsTextfile string
+	metricsOnce     sync.Once
+
+	quiet         bool
+	logFormat     string
+	logFilePath   string
+	logMaxSize    int
+	logMaxBackups int
+	logFile       *log.RotatingFile
 
 	version   = "dev"
 	commit    = "none"
 	buildDate = "unknown"
 )
 
+// newClient returns the EUSurvey client for API commands. serve replaces it
+// so that all jobs share one client and its login session.
+var newClient = func() *client.Client {
+	return client.New(cfg)
+}
+
 var rootCmd = &cobra.Command{
 	Use:   "eusurveymgr",
 	Short: "EUSurvey Management CLI",
 	Long: `eusurveymgr — EUSurvey Management CLI for surveys, results, PDFs, and database queries.
 
+--profile (or EUSURVEYMGR_PROFILE) selects one of the instance profiles of the
+config file, see 'config profiles'.
+
 Environment variables override config file values (avoids exposing credentials):
-  EUSURVEYMGR_WEB_USER, EUSURVEYMGR_WEB_PASSWORD
-  EUSURVEYMGR_DB_HOST, EUSURVEYMGR_DB_NAME, EUSURVEYMGR_DB_USER, EUSURVEYMGR_DB_PASSWORD`,
+EUSURVEYMGR_<KEY> for every scalar or string-list key, e.g. EUSURVEYMGR_WEB_PASSWORD,
+EUSURVEYMGR_DB_PORT, EUSURVEYMGR_API_TOKENS=a,b. See 'config show --origin'.
+
+Logs go to stderr (or --log-file), never to stdout, so command output can be
+piped safely. --log-format json writes one JSON object per line.`,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if verbose {
-			log.SetLogLevel(log.Debug)
+		if err := setupLogging(); err != nil {
+			return err
 		}
-		// Skip config loading for version command
-		if cmd.Name() == "version" {
+		if metricsListen != "" {
+			metricsOnce.Do(func() { go serveMetrics(metricsListen) })
+		}
+		// Skip config loading for version and commands that need none
+		if cmd.Name() == "version" || cmd.Annotations["config"] == "none" {
 			return nil
 		}
 		var err error
-		cfg, err = config.LoadFromFile(cfgFile)
+		cfg, err = config.LoadProfile(cfgFile, activeProfile())
+		if errors.Is(err, fs.ErrNotExist) && dbSource != "" {
+			// Snapshot users usually have no MySQL credentials or config file.
+			cfg, err = config.Default(), nil
+		}
 		if err != nil {
 			return fmt.Errorf("loading config: %w", err)
 		}
@@ -59,13 +98,33 @@ var versionCmd = &cobra.Command{
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "eusurveymgr.json", "Path to config file")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "Config profile to use (default $EUSURVEYMGR_PROFILE or default_profile)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose (debug) output")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Only log warnings and errors")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log format: text or json")
+	rootCmd.PersistentFlags().StringVar(&logFilePath, "log-file", "", "Write logs to this file instead of stderr")
+	rootCmd.PersistentFlags().IntVar(&logMaxSize, "log-max-size", 10, "Rotate the log file when it exceeds this size in MB")
+	rootCmd.PersistentFlags().IntVar(&logMaxBackups, "log-max-backups", 5, "Number of rotated log files to keep")
+	rootCmd.PersistentFlags().StringVar(&dbSource, "source", "", "Run db queries against a SQLite snapshot instead of MySQL")
+	rootCmd.PersistentFlags().BoolVar(&pseudonymize, "pseudonymize", false, "Replace names and emails with keyed pseudonyms in all output")
+	rootCmd.PersistentFlags().StringVar(&metricsListen, "metrics-listen", "", "Serve Prometheus metrics at http://<addr>/metrics while running")
+	rootCmd.PersistentFlags().StringVar(&metricsTextfile, "metrics-textfile", "", "Write Prometheus metrics to this .prom file on exit")
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(surveysCmd)
 	rootCmd.AddCommand(resultsCmd)
 	rootCmd.AddCommand(pdfCmd)
+	rootCmd.AddCommand(answerCmd)
 	rootCmd.AddCommand(dbCmd)
+	rootCmd.AddCommand(gdprCmd)
+	rootCmd.AddCommand(pseudonymCmd)
+	rootCmd.AddCommand(mockServerCmd)
+	rootCmd.AddCommand(hooksCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(apiCmd)
+	rootCmd.AddCommand(uiCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(doctorCmd)
 }
 
 func SetVersion(v, c, d string) {
@@ -74,8 +133,59 @@ func SetVersion(v, c, d string) {
 	buildDate = d
 }
 
+// setupLogging applies the logging flags. The log file stays open for the
+// lifetime of the process; serve jobs pass the same flags and reuse it.
+func setupLogging() error {
+	if verbose && quiet {
+		return fmt.Errorf("--verbose and --quiet are mutually exclusive")
+	}
+	level := log.Info
+	if verbose {
+		level = log.Debug
+	} else if quiet {
+		level = log.Warning
+	}
+	log.SetLogLevel(level)
+
+	format, err := log.ParseFormat(logFormat)
+	if err != nil {
+		return err
+	}
+	log.SetFormat(format)
+
+	if logFilePath == "" || (logFile != nil && logFile.Path == logFilePath) {
+		return nil
+	}
+	f, err := log.OpenRotatingFile(logFilePath, int64(logMaxSize)<<20, logMaxBackups)
+	if err != nil {
+		return err
+	}
+	if logFile != nil {
+		logFile.Close()
+	}
+	logFile = f
+	log.SetLogWriter(f)
+	return nil
+}
+
+// serveMetrics serves /metrics on addr for the lifetime of the process.
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	log.Infof("Metrics on http://%s/metrics", addr)
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		log.Errorf("Metrics listener: %v", err)
+	}
+}
+
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
+	err := rootCmd.Execute()
+	if metricsTextfile != "" {
+		if werr := metrics.WriteTextfile(metricsTextfile); werr != nil {
+			log.Errorf("Writing metrics textfile: %v", werr)
+		}
+	}
+	if err != nil {
 		os.Exit(1)
 	}
 }
\ No newline at end of file
@@ -0,0 +1,340 @@
+package cmd
+
+import (
+	"context"
+	"eusurveymgr/client"
+	"eusurveymgr/config"
+	"eusurveymgr/db"
+	"eusurveymgr/jobs"
+	"eusurveymgr/output"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the recurring jobs from the config as a daemon",
+	Long: `Run the jobs listed under "jobs" in the config on their cron schedules,
+until interrupted. Each job is an eusurveymgr command line:
+
+  "jobs": [
+    {"name": "nightly-export", "schedule": "0 2 * * *",
+     "command": ["results", "export", "--id", "Check4TechnicalSkills", "-y"]},
+    {"name": "missing-report", "schedule": "*/30 8-18 * * mon-fri",
+     "command": ["db", "missing", "--survey", "4609", "--csv"],
+     "output": "/srv/reports/missing-{time}.csv"}
+  ]
+
+Jobs run inside the daemon and share one EUSurvey client (one login session)
+and one database pool. They run one at a time; a job that is still running or
+waiting when it is due again is skipped, so it never runs twice in parallel.
+Every run is recorded in <state_dir>/serve-history.jsonl, see 'serve jobs'.
+Commands that run until interrupted (serve, api, ui, mock-server, hooks run
+without --once, db answers --follow) cannot be jobs.`,
+	Example: `  eusurveymgr serve
+  eusurveymgr serve jobs
+  eusurveymgr serve jobs --history --job nightly-export`,
+	Annotations: map[string]string{"daemon": "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(cfg.Jobs) == 0 {
+			return fmt.Errorf("no jobs configured")
+		}
+		for _, j := range cfg.Jobs {
+			if err := checkJobCommand(j.Command); err != nil {
+				return fmt.Errorf("job %q: %w", j.Name, err)
+			}
+		}
+
+		sched, err := jobs.NewScheduler(cfg.Jobs, jobs.NewHistory(cfg.StateDir), runJob)
+		if err != nil {
+			return err
+		}
+
+		// Share one client and one repository between all jobs.
+		shared := client.New(cfg)
+		newClient = func() *client.Client { return shared }
+		repo := &sharedRepository{open: repositoryFactory}
+		defer repo.close()
+		SetRepositoryFactory(repo.get)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		return sched.Run(ctx)
+	},
+}
+
+var serveJobsCmd = &cobra.Command{
+	Use:   "jobs",
+	Short: "Show configured jobs and their run history",
+	Long: `Show each configured job with its schedule, next run and last run. With
+--history, list the recorded runs instead (newest last).`,
+	Example: `  eusurveymgr serve jobs
+  eusurveymgr serve jobs --history --limit 50
+  eusurveymgr serve jobs --history --job nightly-export --json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showHistory, _ := cmd.Flags().GetBool("history")
+		jobName, _ := cmd.Flags().GetString("job")
+		limit, _ := cmd.Flags().GetInt("limit")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		runs, err := jobs.NewHistory(cfg.StateDir).Read()
+		if err != nil {
+			return err
+		}
+
+		if showHistory {
+			if jobName != "" {
+				runs = slices.DeleteFunc(runs, func(r jobs.Run) bool { return r.Job != jobName })
+			}
+			if limit > 0 && len(runs) > limit {
+				runs = runs[len(runs)-limit:]
+			}
+			return output.Render(os.Stdout, opts, jobRunColumns, runs)
+		}
+
+		sched, err := jobs.NewScheduler(cfg.Jobs, nil, nil)
+		if err != nil {
+			return err
+		}
+		next := sched.Next(time.Now())
+		last := make(map[string]jobs.Run)
+		for _, r := range runs {
+			last[r.Job] = r
+		}
+
+		var status []jobStatus
+		for _, j := range cfg.Jobs {
+			if jobName != "" && j.Name != jobName {
+				continue
+			}
+			s := jobStatus{Job: j, Next: next[j.Name]}
+			if r, ok := last[j.Name]; ok {
+				s.LastRun = &r
+			}
+			status = append(status, s)
+		}
+		return output.Render(os.Stdout, opts, jobStatusColumns, status)
+	},
+}
+
+var jobRunColumns = []output.Column[jobs.Run]{
+	{Name: "job", Value: func(r jobs.Run) any { return r.Job }},
+	{Name: "scheduled", Value: func(r jobs.Run) any { return r.Scheduled }},
+	{Name: "started", Hidden: true, Value: func(r jobs.Run) any { return r.Started }},
+	{Name: "finished", Hidden: true, Value: func(r jobs.Run) any { return r.Finished }},
+	{Name: "status", Value: func(r jobs.Run) any { return r.Status }},
+	{Name: "duration", Value: func(r jobs.Run) any { return r.Duration().Round(time.Second) }},
+	{Name: "output", Value: func(r jobs.Run) any { return r.Output }},
+	{Name: "error", Width: 60, Value: func(r jobs.Run) any { return r.Error }},
+}
+
+type jobStatus struct {
+	config.Job
+	Next    time.Time
+	LastRun *jobs.Run
+}
+
+var jobStatusColumns = []output.Column[jobStatus]{
+	{Name: "job", Value: func(s jobStatus) any { return s.Name }},
+	{Name: "schedule", Value: func(s jobStatus) any { return s.Schedule }},
+	{Name: "next", Value: func(s jobStatus) any { return s.Next }},
+	{Name: "last_run", Value: func(s jobStatus) any {
+		if s.LastRun == nil {
+			return nil
+		}
+		return s.LastRun.Scheduled
+	}},
+	{Name: "status", Value: func(s jobStatus) any {
+		if s.LastRun == nil {
+			return nil
+		}
+		return s.LastRun.Status
+	}},
+	{Name: "command", Value: func(s jobStatus) any { return strings.Join(s.Command, " ") }},
+	{Name: "output", Hidden: true, Value: func(s jobStatus) any { return s.Output }},
+}
+
+// runJob executes a job's command line in-process through the command tree.
+// The scheduler runs one job at a time, which is what makes resetting the
+// global flag state and redirecting os.Stdout here safe.
+// checkJobCommand refuses commands that run until interrupted: jobs run one
+// at a time, so such a job would block every other job for good. Commands
+// mark this with the "daemon" annotation: "true" if they always run until
+// interrupted, "--flag" if they do with that flag, and "unless --flag" if
+// they do without it.
+func checkJobCommand(command []string) error {
+	c, args, err := rootCmd.Find(command)
+	if err != nil {
+		return nil // reported when the job runs
+	}
+	daemon := c.Annotations["daemon"]
+	switch {
+	case daemon == "":
+		return nil
+	case daemon == "true":
+	case strings.HasPrefix(daemon, "unless "):
+		if flagGiven(c, args, strings.TrimPrefix(daemon, "unless ")) {
+			return nil
+		}
+	default:
+		if !flagGiven(c, args, daemon) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q runs until interrupted and cannot be a job", strings.Join(command, " "))
+}
+
+// flagGiven reports whether the boolean flag ("--name") is set to true in
+// args, by name or shorthand.
+func flagGiven(c *cobra.Command, args []string, flag string) bool {
+	f := c.Flags().Lookup(strings.TrimPrefix(flag, "--"))
+	if f == nil {
+		return false
+	}
+	for _, arg := range args {
+		name, value, hasValue := strings.Cut(arg, "=")
+		if name != "--"+f.Name && (f.Shorthand == "" || name != "-"+f.Shorthand) {
+			continue
+		}
+		if !hasValue {
+			return true
+		}
+		b, err := strconv.ParseBool(value)
+		return err != nil || b
+	}
+	return false
+}
+
+func runJob(ctx context.Context, job config.Job, started time.Time) (string, error) {
+	args := []string{"--config", cfgFile}
+	if profile != "" {
+		args = append(args, "--profile", profile)
+	}
+	if dbSource != "" {
+		args = append(args, "--source", dbSource)
+	}
+	if pseudonymize {
+		args = append(args, "--pseudonymize")
+	}
+	if verbose {
+		args = append(args, "--verbose")
+	}
+	if quiet {
+		args = append(args, "--quiet")
+	}
+	args = append(args, "--log-format", logFormat)
+	if logFilePath != "" {
+		args = append(args, "--log-file", logFilePath)
+	}
+	// Passed on so the global values survive the flag reset below.
+	if metricsListen != "" {
+		args = append(args, "--metrics-listen", metricsListen)
+	}
+	if metricsTextfile != "" {
+		args = append(args, "--metrics-textfile", metricsTextfile)
+	}
+	args = append(args, job.Command...)
+
+	var output string
+	stdout, stdin := os.Stdout, os.Stdin
+	defer func() { os.Stdout, os.Stdin = stdout, stdin }()
+	if devNull, err := os.Open(os.DevNull); err == nil {
+		// Confirmation prompts read EOF and decline.
+		defer devNull.Close()
+		os.Stdin = devNull
+	}
+	if job.Output != "" {
+		output = strings.ReplaceAll(job.Output, "{time}", started.Format("20060102-150405"))
+		if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+			return "", err
+		}
+		f, err := os.Create(output)
+		if err != nil {
+			return "", err
+		}
+		defer f.Close()
+		os.Stdout = f
+	}
+
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(args)
+	err := rootCmd.ExecuteContext(ctx)
+	return output, err
+}
+
+// resetFlags restores every flag of the command tree to its default, so a
+// job does not inherit flag values from the previous one.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			sv.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, c := range cmd.Commands() {
+		resetFlags(c)
+	}
+}
+
+// sharedRepository opens the repository on first use and hands it to every
+// job. Jobs close what they open, so Close is a no-op until the daemon exits.
+type sharedRepository struct {
+	open RepositoryFactory
+	mu   sync.Mutex
+	repo db.SurveyRepository
+}
+
+func (s *sharedRepository) get(cfg *config.Configuration) (db.SurveyRepository, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.repo == nil {
+		repo, err := s.open(cfg)
+		if err != nil {
+			return nil, err
+		}
+		s.repo = repo
+	}
+	return nopCloseRepository{s.repo}, nil
+}
+
+func (s *sharedRepository) close() {
+	if s.repo != nil {
+		s.repo.Close()
+	}
+}
+
+type nopCloseRepository struct {
+	db.SurveyRepository
+}
+
+func (nopCloseRepository) Close() error {
+	return nil
+}
+
+func init() {
+	config.ValidateJob = checkJobCommand
+	serveJobsCmd.Flags().Bool("history", false, "List recorded runs instead of the job overview")
+	serveJobsCmd.Flags().String("job", "", "Only this job")
+	serveJobsCmd.Flags().Int("limit", 20, "With --history, number of most recent runs (0 = all)")
+	addOutputFlags(serveJobsCmd)
+
+	serveCmd.AddCommand(serveJobsCmd)
+}
@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckJobCommand(t *testing.T) {
+	tests := []struct {
+		command string
+		daemon  bool
+	}{
+		{"serve", true},
+		{"mock-server --listen 127.0.0.1:8089", true},
+		{"api", true},
+		{"ui --listen 0.0.0.0:8081", true},
+		{"hooks run", true},
+		{"hooks run --interval 1m", true},
+		{"hooks run --once", false},
+		{"hooks run --once=false", true},
+		{"hooks retry", false},
+		{"db answers --survey 4609", false},
+		{"db answers --survey 4609 --follow", true},
+		{"db answers -f --survey 4609", true},
+		{"db answers --follow=false --survey 4609", false},
+		{"serve jobs", false},
+		{"db missing --survey 4609 --csv", false},
+	}
+	for _, tt := range tests {
+		err := checkJobCommand(strings.Fields(tt.command))
+		if (err != nil) != tt.daemon {
+			t.Errorf("%s: got %v, want daemon=%v", tt.command, err, tt.daemon)
+		}
+	}
+}
@@ -1,11 +1,13 @@
 package cmd
 
 import (
-	"encoding/json"
 	"eusurveymgr/client"
+	"eusurveymgr/output"
 	"fmt"
 	"os"
-	"text/tabwriter"
+	"slices"
+	"strings"
+	"unicode"
 
 	"github.com/spf13/cobra"
 )
@@ -16,81 +18,258 @@ var surveysCmd = &cobra.Command{
 	Long:  "Query the EUSurvey WebService API to list surveys and retrieve metadata.",
 }
 
+var surveyColumns = []output.Column[client.Survey]{
+	{Name: "uid", Hidden: true, Value: func(s client.Survey) any { return s.UID }},
+	{Name: "alias", Value: func(s client.Survey) any { return s.Alias }},
+	{Name: "title", Width: 60, Value: func(s client.Survey) any { return s.Title }},
+}
+
 var surveysListCmd = &cobra.Command{
 	Use:   "list",
 	Short: "List surveys",
 	Long:  "List all surveys for the authenticated user via the WebService API.",
 	Example: `  eusurveymgr surveys list
-  eusurveymgr surveys list --json`,
+  eusurveymgr surveys list --json
+  eusurveymgr surveys list --columns uid,alias --no-headers`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		jsonOut, _ := cmd.Flags().GetBool("json")
-		c := client.New(cfg)
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+		c := newClient()
 
 		list, err := c.GetSurveys()
 		if err != nil {
 			return err
 		}
+		return output.Render(os.Stdout, opts, surveyColumns, list.Surveys)
+	},
+}
+
+var surveyMetadataColumns = []output.Column[*client.SurveyMetadata]{
+	{Name: "id", Value: func(m *client.SurveyMetadata) any { return m.ID }},
+	{Name: "alias", Value: func(m *client.SurveyMetadata) any { return m.Alias }},
+	{Name: "title", Value: func(m *client.SurveyMetadata) any { return m.Title }},
+	{Name: "survey_type", Value: func(m *client.SurveyMetadata) any { return m.SurveyType }},
+	{Name: "status", Value: func(m *client.SurveyMetadata) any { return m.Status }},
+	{Name: "language", Value: func(m *client.SurveyMetadata) any { return m.Language }},
+	{Name: "security", Value: func(m *client.SurveyMetadata) any { return m.Security }},
+	{Name: "visibility", Value: func(m *client.SurveyMetadata) any { return m.Visibility }},
+	{Name: "results", Value: func(m *client.SurveyMetadata) any { return m.Results }},
+	{Name: "contact", Value: func(m *client.SurveyMetadata) any { return m.Contact }},
+	{Name: "start", Value: func(m *client.SurveyMetadata) any { return m.Start }},
+	{Name: "end", Value: func(m *client.SurveyMetadata) any { return m.End }},
+}
+
+// metadataKeys names the documented getSurveyMetadata fields as the typed
+// columns above; every other field is named by metadataKey.
+var metadataKeys = map[string]string{
+	"id": "id", "alias": "alias", "SurveyType": "survey_type", "Title": "title",
+	"PivotLanguage": "language", "Contact": "contact", "Status": "status", "Start": "start",
+	"End": "end", "Results": "results", "Security": "security", "Visibility": "visibility",
+}
 
-		if jsonOut {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			return enc.Encode(list.Surveys)
+// metadataKey converts a client.MetadataField name to snake_case, segment by
+// segment: "Languages.Language[2]@code" becomes "languages.language[2]@code".
+func metadataKey(name string) string {
+	if key, ok := metadataKeys[name]; ok {
+		return key
+	}
+	var b strings.Builder
+	prev := rune(0)
+	for _, r := range name {
+		if unicode.IsUpper(r) {
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
 		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
 
-		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
-		fmt.Fprintln(w, "ALIAS\tTITLE")
-		for _, s := range list.Surveys {
-			fmt.Fprintf(w, "%s\t%s\n", s.Alias, s.Title)
+// metadataColumns returns the typed columns followed by one column for each
+// field of meta that has no typed counterpart.
+func metadataColumns(meta *client.SurveyMetadata) []output.Column[*client.SurveyMetadata] {
+	cols := slices.Clone(surveyMetadataColumns)
+	for _, f := range meta.Fields {
+		if _, ok := metadataKeys[f.Name]; ok {
+			continue
 		}
-		return w.Flush()
-	},
+		value := f.Value
+		cols = append(cols, output.Column[*client.SurveyMetadata]{Name: metadataKey(f.Name),
+			Value: func(*client.SurveyMetadata) any { return value }})
+	}
+	return cols
 }
 
 var surveysInfoCmd = &cobra.Command{
 	Use:   "info",
 	Short: "Get survey metadata",
-	Long:  "Retrieve detailed metadata for a survey by its alias (shortname).",
+	Long: `Retrieve detailed metadata for a survey by its alias (shortname).
+
+Every element and attribute of the getSurveyMetadata response is shown: the
+documented ones first, then any others with their element path in snake_case
+(e.g. languages.language[2]). --raw prints the XML itself, after replacing
+invalid UTF-8.`,
 	Example: `  eusurveymgr surveys info --alias Check4SkillsInRomana
-  eusurveymgr surveys info --alias Check4SkillsInEnglish --json`,
+  eusurveymgr surveys info --alias Check4SkillsInEnglish --json
+  eusurveymgr surveys info --alias Check4SkillsInEnglish --template '{{.status}} {{.results}}'
+  eusurveymgr surveys info --alias Check4SkillsInEnglish --raw`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		alias, _ := cmd.Flags().GetString("alias")
-		jsonOut, _ := cmd.Flags().GetBool("json")
-		c := client.New(cfg)
+		raw, _ := cmd.Flags().GetBool("raw")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+		c := newClient()
 
 		meta, err := c.GetSurveyMetadata(alias)
 		if err != nil {
 			return err
 		}
+		if raw {
+			_, err := os.Stdout.Write(meta.Raw)
+			return err
+		}
+		return output.RenderRecord(os.Stdout, opts, metadataColumns(meta), meta)
+	},
+}
+
+// variantFields differ between the language variants of one questionnaire
+// by design. surveys diff compares them only when --fields names them; --all
+// lists them without counting them.
+var variantFields = []string{"id", "alias", "title", "language"}
+
+// metadataDiff is one metadata field across the compared surveys.
+type metadataDiff struct {
+	Field  string
+	Values []string
+	Same   bool
+}
+
+var surveysDiffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Compare the metadata of surveys",
+	Long: `Fetch the metadata of two or more surveys and compare it field by field, for
+example to check that all language variants of a questionnaire have the same
+security and visibility. Only the fields that differ are listed, unless --all
+is given; --fields restricts the comparison. A field missing from a survey's
+metadata is shown as "-". The fields that tell variants apart (id, alias, title
+and language) are not compared unless named by --fields; --all lists them
+without counting them. Exits non-zero if any compared field differs.`,
+	Example: `  eusurveymgr surveys diff --alias Check4SkillsInEnglish --alias Check4SkillsInRomana
+  eusurveymgr surveys diff --alias Check4SkillsInEnglish --alias Check4SkillsInRomana --fields security,visibility
+  eusurveymgr surveys diff --alias a --alias b --alias c --all --format csv`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		aliases, _ := cmd.Flags().GetStringArray("alias")
+		fields, _ := cmd.Flags().GetStringSlice("fields")
+		all, _ := cmd.Flags().GetBool("all")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+		if len(aliases) < 2 {
+			return fmt.Errorf("at least two --alias are needed")
+		}
+		c := newClient()
+
+		var keys []string
+		values := make([]map[string]string, len(aliases))
+		for i, alias := range aliases {
+			meta, err := c.GetSurveyMetadata(alias)
+			if err != nil {
+				return fmt.Errorf("%s: %w", alias, err)
+			}
+			values[i] = make(map[string]string)
+			for _, f := range meta.Fields {
+				key := metadataKey(f.Name)
+				if !slices.Contains(keys, key) {
+					keys = append(keys, key)
+				}
+				values[i][key] = f.Value
+			}
+		}
+		if len(fields) > 0 {
+			for _, f := range fields {
+				if !slices.Contains(keys, f) {
+					return fmt.Errorf("unknown field %q (available: %s)", f, strings.Join(keys, ", "))
+				}
+			}
+			keys = fields
+		}
+		compared := func(key string) bool {
+			return len(fields) > 0 || !slices.Contains(variantFields, key)
+		}
+		if !all {
+			keys = slices.DeleteFunc(keys, func(key string) bool { return !compared(key) })
+		}
+
+		var rows []metadataDiff
+		differ, total := 0, 0
+		for _, key := range keys {
+			d := metadataDiff{Field: key, Same: true}
+			for i := range aliases {
+				v, ok := values[i][key]
+				if !ok {
+					v = "-"
+				}
+				d.Values = append(d.Values, v)
+				if v != d.Values[0] {
+					d.Same = false
+				}
+			}
+			if compared(key) {
+				total++
+				if !d.Same {
+					differ++
+				}
+			}
+			if all || !d.Same {
+				rows = append(rows, d)
+			}
+		}
 
-		if jsonOut {
-			enc := json.NewEncoder(os.Stdout)
-			enc.SetIndent("", "  ")
-			return enc.Encode(meta)
-		}
-
-		fmt.Printf("ID:         %s\n", meta.ID)
-		fmt.Printf("Alias:      %s\n", meta.Alias)
-		fmt.Printf("Title:      %s\n", meta.Title)
-		fmt.Printf("Type:       %s\n", meta.SurveyType)
-		fmt.Printf("Status:     %s\n", meta.Status)
-		fmt.Printf("Language:   %s\n", meta.Language)
-		fmt.Printf("Security:   %s\n", meta.Security)
-		fmt.Printf("Visibility: %s\n", meta.Visibility)
-		fmt.Printf("Results:    %d\n", meta.Results)
-		fmt.Printf("Contact:    %s\n", meta.Contact)
-		fmt.Printf("Start:      %s\n", meta.Start)
-		fmt.Printf("End:        %s\n", meta.End)
+		cols := []output.Column[metadataDiff]{
+			{Name: "field", Value: func(d metadataDiff) any { return d.Field }},
+		}
+		for i, alias := range aliases {
+			cols = append(cols, output.Column[metadataDiff]{Name: alias, Width: 40,
+				Value: func(d metadataDiff) any { return d.Values[i] }})
+		}
+		cols = append(cols, output.Column[metadataDiff]{Name: "same", Hidden: !all,
+			Value: func(d metadataDiff) any { return d.Same }})
+		if err := output.Render(os.Stdout, opts, cols, rows); err != nil {
+			return err
+		}
+		if differ > 0 {
+			return fmt.Errorf("%d of %d fields differ", differ, total)
+		}
 		return nil
 	},
 }
 
 func init() {
-	surveysListCmd.Flags().Bool("json", false, "JSON output")
+	addOutputFlags(surveysListCmd)
 
 	surveysInfoCmd.Flags().String("alias", "", "Survey alias/shortname")
-	surveysInfoCmd.Flags().Bool("json", false, "JSON output")
+	surveysInfoCmd.Flags().Bool("raw", false, "Print the metadata XML as received (sanitised)")
+	addOutputFlags(surveysInfoCmd)
 	surveysInfoCmd.MarkFlagRequired("alias")
+	surveysInfoCmd.MarkFlagsMutuallyExclusive("raw", "format")
+	surveysInfoCmd.MarkFlagsMutuallyExclusive("raw", "json")
+	surveysInfoCmd.MarkFlagsMutuallyExclusive("raw", "template")
+
+	surveysDiffCmd.Flags().StringArray("alias", nil, "Survey alias/shortname (repeat for each survey)")
+	surveysDiffCmd.Flags().StringSlice("fields", nil, "Only compare these fields (comma-separated)")
+	surveysDiffCmd.Flags().Bool("all", false, "List every field, not only the differing ones")
+	addOutputFlags(surveysDiffCmd)
+	surveysDiffCmd.MarkFlagRequired("alias")
 
 	surveysCmd.AddCommand(surveysListCmd)
 	surveysCmd.AddCommand(surveysInfoCmd)
-}
\ No newline at end of file
+	surveysCmd.AddCommand(surveysDiffCmd)
+}
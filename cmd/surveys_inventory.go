@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"eusurveymgr/client"
+	"eusurveymgr/inventory"
+	"eusurveymgr/log"
+	"eusurveymgr/output"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+// metaValue returns a metadata value of e, or nil without metadata.
+func metaValue(e inventory.Entry, value func(m *client.SurveyMetadata) any) any {
+	if e.Meta == nil {
+		return nil
+	}
+	return value(e.Meta)
+}
+
+var inventoryColumns = []output.Column[inventory.Entry]{
+	{Name: "id", Value: func(e inventory.Entry) any {
+		if e.DB != nil {
+			return e.DB.SurveyID
+		}
+		return metaValue(e, func(m *client.SurveyMetadata) any { return m.ID })
+	}},
+	{Name: "uid", Hidden: true, Value: func(e inventory.Entry) any { return e.UID }},
+	{Name: "alias", Value: func(e inventory.Entry) any { return e.Alias }},
+	{Name: "title", Hidden: true, Value: func(e inventory.Entry) any { return e.Title }},
+	{Name: "language", Value: func(e inventory.Entry) any {
+		return metaValue(e, func(m *client.SurveyMetadata) any { return m.Language })
+	}},
+	{Name: "survey_type", Value: func(e inventory.Entry) any {
+		return metaValue(e, func(m *client.SurveyMetadata) any { return m.SurveyType })
+	}},
+	{Name: "status", Value: func(e inventory.Entry) any {
+		return metaValue(e, func(m *client.SurveyMetadata) any { return m.Status })
+	}},
+	{Name: "start", Value: func(e inventory.Entry) any {
+		return metaValue(e, func(m *client.SurveyMetadata) any { return m.Start })
+	}},
+	{Name: "end", Value: func(e inventory.Entry) any {
+		return metaValue(e, func(m *client.SurveyMetadata) any { return m.End })
+	}},
+	{Name: "api_results", Value: func(e inventory.Entry) any {
+		return metaValue(e, func(m *client.SurveyMetadata) any { return m.Results })
+	}},
+	{Name: "db_answers", Value: func(e inventory.Entry) any {
+		if e.DB == nil {
+			return nil
+		}
+		return e.DB.NumAnswers
+	}},
+	{Name: "db_published", Hidden: true, Value: func(e inventory.Entry) any {
+		if e.DB == nil {
+			return nil
+		}
+		return e.DB.Published
+	}},
+	{Name: "flags", Value: func(e inventory.Entry) any { return e.Flags }},
+	{Name: "metadata_error", Hidden: true, Value: func(e inventory.Entry) any { return e.MetaError }},
+}
+
+var surveysInventoryCmd = &cobra.Command{
+	Use:   "inventory",
+	Short: "Join the API and database views of all surveys",
+	Long: `List every survey with its WebService metadata and its database counterpart
+in one table: the API survey list (getMySurveys) is joined with 'db surveys'
+on SURVEY_UID (or alias), and the metadata of each API survey is fetched
+concurrently (--concurrency requests at a time).
+
+The flags column names each difference: not in DB, not in API, alias
+differs, ID differs (the metadata ID is not the latest SURVEY_ID), status
+differs (published vs. the database flag), results differ (API result count
+vs. database answer sets) and no metadata.
+
+Each survey costs one WebService request. With api_daily_budget set, only as
+many metadata requests are made as today's budget has left; the remaining
+surveys are listed without metadata.`,
+	Example: `  eusurveymgr surveys inventory
+  eusurveymgr surveys inventory --flagged
+  eusurveymgr --source survey-4609.sqlite surveys inventory --format csv`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		concurrency, _ := cmd.Flags().GetInt("concurrency")
+		flaggedOnly, _ := cmd.Flags().GetBool("flagged")
+		opts, err := outputOptions(cmd)
+		if err != nil {
+			return err
+		}
+
+		repo, err := openRepository()
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+
+		surveys, err := repo.ListSurveys()
+		if err != nil {
+			return err
+		}
+		inv := &inventory.Inventory{Client: newClient(), Concurrency: concurrency}
+		entries, err := inv.Build(surveys)
+		if err != nil {
+			return err
+		}
+
+		flagged := 0
+		var shown []inventory.Entry
+		for _, e := range entries {
+			if len(e.Flags) > 0 {
+				flagged++
+			}
+			if !flaggedOnly || len(e.Flags) > 0 {
+				shown = append(shown, e)
+			}
+		}
+		if err := output.Render(os.Stdout, opts, inventoryColumns, shown); err != nil {
+			return err
+		}
+		log.Infof("%d surveys, %d with differences", len(entries), flagged)
+		return nil
+	},
+}
+
+func init() {
+	surveysInventoryCmd.Flags().Int("concurrency", 4, "Metadata requests in flight")
+	surveysInventoryCmd.Flags().Bool("flagged", false, "Only list surveys with differences")
+	addOutputFlags(surveysInventoryCmd)
+
+	surveysCmd.AddCommand(surveysInventoryCmd)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"eusurveymgr/ui"
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var uiCmd = &cobra.Command{
+	Use:   "ui",
+	Short: "Serve the web dashboard for coordinators",
+	Long: `Serve a read-only web dashboard: the survey list, the respondents of each
+survey, the responses of each respondent, and one-click answer PDF downloads
+(generated through EUSurvey like 'pdf answer').
+
+Sign-in accounts are the ui_users of the config. Sessions last 8 hours.
+Templates and styles are built into the binary. Data comes from MySQL (or
+--source); with --pseudonymize, names and emails are pseudonymised.
+
+The dashboard speaks plain HTTP: listen on localhost or put it behind a TLS
+reverse proxy.`,
+	Example: `  eusurveymgr ui
+  eusurveymgr ui --listen 0.0.0.0:8081`,
+	Annotations: map[string]string{"daemon": "true"},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		listen, _ := cmd.Flags().GetString("listen")
+		if len(cfg.UIUsers) == 0 {
+			return fmt.Errorf("ui_users must be set in the config")
+		}
+		p, err := identityPseudonymizer()
+		if err != nil {
+			return err
+		}
+
+		repo, err := openRepository()
+		if err != nil {
+			return err
+		}
+		defer repo.Close()
+
+		s, err := ui.New(repo, newClient(), cfg.UIUsers)
+		if err != nil {
+			return err
+		}
+		s.PDFTimeout = cfg.TimeoutSeconds
+		s.Pseudonymizer = p
+		return listenAndServe(listen, s)
+	},
+}
+
+func init() {
+	uiCmd.Flags().String("listen", "127.0.0.1:8081", "Listen address")
+}
@@ -2,8 +2,11 @@ package config
 
 import (
 	"encoding/json"
+	"eusurveymgr/log"
 	"fmt"
 	"os"
+	"slices"
+	"strings"
 )
 
 type Configuration struct {
@@ -18,59 +21,339 @@ type Configuration struct {
 	OutputDir      string `json:"output_dir"`
 	TimeoutSeconds int    `json:"timeout_seconds"`
 	InsecureTLS    bool   `json:"insecure_tls"`
+
+	// WebService requests allowed per day (0: no limit), counted in
+	// StateDir. Keep it below the server's webservice.maxrequestsperday.
+	APIDailyBudget int `json:"api_daily_budget,omitempty"`
+
+	// Account with write access, used only by 'db settings set'. Empty
+	// falls back to db_user/db_password.
+	DBAdminUser     string `json:"db_admin_user,omitempty"`
+	DBAdminPassword string `json:"db_admin_password,omitempty"`
+
+	// Indirect secret sources, see secrets.go. Commands are argument lists
+	// run without a shell.
+	WebPasswordFile        string   `json:"web_password_file,omitempty"`
+	WebPasswordCommand     []string `json:"web_password_command,omitempty"`
+	DBPasswordFile         string   `json:"db_password_file,omitempty"`
+	DBPasswordCommand      []string `json:"db_password_command,omitempty"`
+	DBAdminPasswordFile    string   `json:"db_admin_password_file,omitempty"`
+	DBAdminPasswordCommand []string `json:"db_admin_password_command,omitempty"`
+	PseudonymKeyFile       string   `json:"pseudonym_key_file,omitempty"`
+	PseudonymKeyCommand    []string `json:"pseudonym_key_command,omitempty"`
+	APITokensFile          string   `json:"api_tokens_file,omitempty"`
+	APITokensCommand       []string `json:"api_tokens_command,omitempty"`
+	// Encrypted file holding any of the secrets above ('config secrets').
+	SecretsFile string `json:"secrets_file,omitempty"`
+
+	// Pseudonymisation of identity fields (--pseudonymize)
+	PseudonymKey    string   `json:"pseudonym_key"`
+	PseudonymMode   string   `json:"pseudonym_mode"`
+	ReidentifyUsers []string `json:"reidentify_users"`
+
+	// Handlers fired on new answer sets (hooks run). Delivery state is kept
+	// in StateDir.
+	StateDir string `json:"state_dir"`
+	Hooks    []Hook `json:"hooks"`
+
+	// Bearer tokens accepted by the REST API ('api')
+	APITokens []string `json:"api_tokens"`
+
+	// Accounts for the web dashboard ('ui')
+	UIUsers []UIUser `json:"ui_users"`
+
+	// Recurring jobs run by 'serve'
+	Jobs []Job `json:"jobs"`
+
+	// Layout of locally rendered answer PDFs ('pdf answer --local'): header
+	// text and PNG/JPEG logo on every page, and a TrueType font replacing
+	// the built-in Go fonts.
+	PDFHeader string `json:"pdf_header,omitempty"`
+	PDFLogo   string `json:"pdf_logo,omitempty"`
+	PDFFont   string `json:"pdf_font,omitempty"`
+
+	// Named instances (e.g. "test", "prod"). Each profile is an object with
+	// any of the keys above; they replace the top-level values, which act as
+	// defaults for every profile.
+	Profiles       map[string]json.RawMessage `json:"profiles,omitempty"`
+	DefaultProfile string                     `json:"default_profile,omitempty"`
+	// Profile is the name of the profile that was applied, if any.
+	Profile string `json:"-"`
+
+	// origins records where each key's value came from, see Origin.
+	origins map[string]string
+}
+
+// Hook is one handler for new answer sets of a survey: an HTTP POST of a JSON
+// payload to URL, or a local Command run with the payload in its environment.
+type Hook struct {
+	Name           string            `json:"name"`
+	Survey         int64             `json:"survey"`
+	URL            string            `json:"url,omitempty"`
+	Headers        map[string]string `json:"headers,omitempty"`
+	Command        []string          `json:"command,omitempty"`
+	MaxAttempts    int               `json:"max_attempts,omitempty"`
+	TimeoutSeconds int               `json:"timeout_seconds,omitempty"`
+	// Headers kept out of the config (e.g. Authorization), see secrets.go.
+	HeadersFile    string   `json:"headers_file,omitempty"`
+	HeadersCommand []string `json:"headers_command,omitempty"`
+}
+
+// UIUser is a login of the web dashboard.
+type UIUser struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	// Indirect password sources, see secrets.go.
+	PasswordFile    string   `json:"password_file,omitempty"`
+	PasswordCommand []string `json:"password_command,omitempty"`
+}
+
+// Job is a recurring eusurveymgr command run by 'serve'. Command holds the
+// arguments after "eusurveymgr", e.g. ["results", "export", "--id", "X", "-y"].
+// Output, if set, receives the command's standard output; "{time}" in it is
+// replaced by the start time (YYYYMMDD-HHMMSS).
+type Job struct {
+	Name     string   `json:"name"`
+	Schedule string   `json:"schedule"`
+	Command  []string `json:"command"`
+	Output   string   `json:"output,omitempty"`
 }
 
+// LoadFromFile loads the profile named by EUSURVEYMGR_PROFILE, or the
+// default profile of the file.
 func LoadFromFile(filePath string) (*Configuration, error) {
+	return LoadProfile(filePath, os.Getenv("EUSURVEYMGR_PROFILE"))
+}
+
+// LoadProfile loads filePath with the named profile applied and its secrets
+// resolved. An empty name selects default_profile, or no profile if the file
+// has none.
+func LoadProfile(filePath, profile string) (*Configuration, error) {
+	c, err := ReadProfile(filePath, profile)
+	if err != nil {
+		return nil, err
+	}
+	if err := resolveSecrets(c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// ReadProfile is LoadProfile without resolving the secrets: no _command is
+// run, no _file read and the secrets file is not unlocked. It serves
+// commands that only show or check the settings.
+func ReadProfile(filePath, profile string) (*Configuration, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
 		return nil, err
 	}
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(content, &top); err != nil {
+		return nil, err
+	}
 	var c Configuration
-	err = json.Unmarshal(content, &c)
-	if err != nil {
+	if err := json.Unmarshal(content, &c); err != nil {
+		return nil, err
+	}
+	warnUnknownKeys(top, filePath)
+	origins := make(map[string]string)
+	for k := range top {
+		origins[k] = "file"
+	}
+	if profile == "" {
+		profile = c.DefaultProfile
+	}
+	if profile != "" {
+		raw, ok := c.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q (profiles: %s)", profile, strings.Join(c.ProfileNames(), ", "))
+		}
+		// Merge key by key so that a profile replaces lists and objects
+		// instead of merging into the top-level ones.
+		var overlay map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &overlay); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", profile, err)
+		}
+		warnUnknownKeys(overlay, filePath+" profile "+profile)
+		for k, v := range overlay {
+			if k == "profiles" || k == "default_profile" {
+				return nil, fmt.Errorf("profile %q: %q is only allowed at the top level", profile, k)
+			}
+			top[k] = v
+			origins[k] = "profile " + profile
+		}
+		// A secret given in the profile replaces the top-level one, whatever
+		// its form (value, _file or _command).
+		for _, key := range secretKeys {
+			variants := []string{key, key + "_file", key + "_command"}
+			for _, k := range variants {
+				if _, ok := overlay[k]; !ok {
+					continue
+				}
+				for _, other := range variants {
+					if _, ok := overlay[other]; !ok {
+						delete(top, other)
+						delete(origins, other)
+					}
+				}
+				break
+			}
+		}
+		merged, err := json.Marshal(top)
+		if err != nil {
+			return nil, err
+		}
+		c = Configuration{}
+		if err := json.Unmarshal(merged, &c); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", profile, err)
+		}
+		c.Profile = profile
+	}
+	c.origins = origins
+	applyDefaults(&c)
+	if err := applyEnvOverrides(&c); err != nil {
 		return nil, err
 	}
+	return &c, nil
+}
+
+// warnUnknownKeys logs the keys of a config object that are not config keys,
+// usually typos; 'config validate' rejects them.
+func warnUnknownKeys(obj map[string]json.RawMessage, where string) {
+	for k := range obj {
+		if !knownKey(k) {
+			log.Warnf("CONFIG -- unknown key %q in %s is ignored", k, where)
+		}
+	}
+}
+
+// Origin returns where the value of key came from: "file", "profile <name>",
+// "env <VARIABLE>" or "default".
+func (c *Configuration) Origin(key string) string {
+	if o, ok := c.origins[key]; ok {
+		return o
+	}
+	return "default"
+}
+
+func (c *Configuration) setOrigin(key, origin string) {
+	if c.origins == nil {
+		c.origins = make(map[string]string)
+	}
+	c.origins[key] = origin
+}
+
+// ProfileNames returns the names of the configured profiles, sorted.
+func (c *Configuration) ProfileNames() []string {
+	var names []string
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Default returns a configuration with default values and environment
+// overrides only, for commands that can run without a config file.
+func Default() *Configuration {
+	var c Configuration
+	applyDefaults(&c)
+	if err := applyEnvOverrides(&c); err != nil {
+		log.Warnf("CONFIG -- %v", err)
+	}
+	if err := resolveSecrets(&c); err != nil {
+		log.Warnf("CONFIG -- %v", err)
+	}
+	return &c
+}
+
+func applyDefaults(c *Configuration) {
+	if c.DBPort == 0 {
+		c.DBPort = 3306
+	}
 	if c.TimeoutSeconds == 0 {
 		c.TimeoutSeconds = 30
 	}
 	if c.OutputDir == "" {
 		c.OutputDir = "."
 	}
-	applyEnvOverrides(&c)
-	return &c, nil
+	if c.StateDir == "" {
+		c.StateDir = c.OutputDir
+	}
+	for i := range c.Hooks {
+		if c.Hooks[i].MaxAttempts == 0 {
+			c.Hooks[i].MaxAttempts = 5
+		}
+		if c.Hooks[i].TimeoutSeconds == 0 {
+			c.Hooks[i].TimeoutSeconds = c.TimeoutSeconds
+		}
+	}
 }
 
-// applyEnvOverrides overrides config fields from EUSURVEYMGR_* environment
-// variables. This avoids exposing credentials on the command line.
-func applyEnvOverrides(c *Configuration) {
-	if v := os.Getenv("EUSURVEYMGR_WEB_USER"); v != "" {
-		c.WebUser = v
+// Masked returns a copy of the configuration with passwords, keys and tokens
+// replaced by "***" and the source of each secret, for display.
+func (cfg *Configuration) Masked() *Configuration {
+	safe := *cfg
+	// Show where each secret came from instead of its value.
+	mask := func(key string) string {
+		return "*** (" + cfg.Origin(key) + ")"
 	}
-	if v := os.Getenv("EUSURVEYMGR_WEB_PASSWORD"); v != "" {
-		c.WebPassword = v
+	safe.WebPassword = "***"
+	if cfg.WebPassword != "" {
+		safe.WebPassword = mask("web_password")
 	}
-	if v := os.Getenv("EUSURVEYMGR_DB_HOST"); v != "" {
-		c.DBHost = v
+	safe.DBPassword = "***"
+	if cfg.DBPassword != "" {
+		safe.DBPassword = mask("db_password")
+	}
+	if cfg.DBAdminPassword != "" {
+		safe.DBAdminPassword = mask("db_admin_password")
 	}
-	if v := os.Getenv("EUSURVEYMGR_DB_NAME"); v != "" {
-		c.DBName = v
+	if safe.PseudonymKey != "" {
+		safe.PseudonymKey = mask("pseudonym_key")
 	}
-	if v := os.Getenv("EUSURVEYMGR_DB_USER"); v != "" {
-		c.DBUser = v
+	if len(safe.APITokens) > 0 {
+		safe.APITokens = []string{mask("api_tokens")}
 	}
-	if v := os.Getenv("EUSURVEYMGR_DB_PASSWORD"); v != "" {
-		c.DBPassword = v
+	safe.Profiles = nil
+	safe.UIUsers = nil
+	for _, u := range cfg.UIUsers {
+		u.Password = "***"
+		if origin := cfg.Origin("ui_users." + u.Username + ".password"); origin != "default" {
+			u.Password = "*** (" + origin + ")"
+		}
+		safe.UIUsers = append(safe.UIUsers, u)
 	}
+	// Header values are often tokens (Authorization: Bearer ...).
+	safe.Hooks = nil
+	for _, h := range cfg.Hooks {
+		if len(h.Headers) > 0 {
+			masked := "***"
+			if origin := cfg.Origin("hooks." + h.Name + ".headers"); origin != "default" {
+				masked = "*** (" + origin + ")"
+			}
+			headers := make(map[string]string, len(h.Headers))
+			for name := range h.Headers {
+				headers[name] = masked
+			}
+			h.Headers = headers
+		}
+		safe.Hooks = append(safe.Hooks, h)
+	}
+	return &safe
 }
 
+// PrintConfig writes the masked configuration to stderr (it is shown with
+// --verbose and must not mix with command output).
 func PrintConfig(cfg *Configuration) {
-	safe := *cfg
-	safe.WebPassword = "***"
-	safe.DBPassword = "***"
-	c, err := json.MarshalIndent(safe, "", "  ")
+	c, err := json.MarshalIndent(cfg.Masked(), "", "  ")
 	if err != nil {
-		fmt.Printf("Error marshalling config: %v\n", err)
+		fmt.Fprintf(os.Stderr, "Error marshalling config: %v\n", err)
+		return
+	}
+	if cfg.Profile != "" {
+		fmt.Fprintf(os.Stderr, "Configuration (profile %s):\n%s\n", cfg.Profile, string(c))
 		return
 	}
-	fmt.Printf("Configuration:\n%s\n", string(c))
+	fmt.Fprintf(os.Stderr, "Configuration:\n%s\n", string(c))
 }
\ No newline at end of file
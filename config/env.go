@@ -0,0 +1,136 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables that override config
+// fields: EUSURVEYMGR_ plus the upper-cased JSON key, e.g.
+// EUSURVEYMGR_DB_PORT for db_port.
+const EnvPrefix = "EUSURVEYMGR_"
+
+// field is a top-level config key and the struct field it is stored in.
+type field struct {
+	key   string
+	index int
+}
+
+// fields returns the config keys in declaration order.
+func fields() []field {
+	t := reflect.TypeOf(Configuration{})
+	var out []field
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if key == "" || key == "-" {
+			continue
+		}
+		out = append(out, field{key: key, index: i})
+	}
+	return out
+}
+
+// knownKey reports whether key is a top-level config key.
+func knownKey(key string) bool {
+	for _, f := range fields() {
+		if f.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvName returns the environment variable that overrides key, or "" if the
+// key cannot be set from the environment (lists of objects, profiles).
+func EnvName(key string) string {
+	if key == "default_profile" {
+		// Selected with EUSURVEYMGR_PROFILE instead.
+		return ""
+	}
+	for _, f := range fields() {
+		if f.key == key && envSettable(reflect.TypeOf(Configuration{}).Field(f.index).Type) {
+			return EnvPrefix + strings.ToUpper(key)
+		}
+	}
+	return ""
+}
+
+func envSettable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.String, reflect.Int, reflect.Bool:
+		return true
+	case reflect.Slice:
+		return t.Elem().Kind() == reflect.String
+	}
+	return false
+}
+
+// Setting is one config key with its (masked) value, where the value came
+// from and the environment variable that overrides it.
+type Setting struct {
+	Key    string `json:"key"`
+	Value  any    `json:"value"`
+	Origin string `json:"origin"`
+	Env    string `json:"env,omitempty"`
+}
+
+// Settings lists every config key in declaration order, with secrets masked.
+func (c *Configuration) Settings() []Setting {
+	v := reflect.ValueOf(c.Masked()).Elem()
+	var out []Setting
+	for _, f := range fields() {
+		if f.key == "profiles" {
+			continue
+		}
+		out = append(out, Setting{Key: f.key, Value: v.Field(f.index).Interface(),
+			Origin: c.Origin(f.key), Env: EnvName(f.key)})
+	}
+	return out
+}
+
+// applyEnvOverrides overrides config fields from EUSURVEYMGR_* environment
+// variables. This avoids exposing credentials on the command line. Lists of
+// strings are comma-separated.
+func applyEnvOverrides(c *Configuration) error {
+	v := reflect.ValueOf(c).Elem()
+	for _, f := range fields() {
+		name := EnvName(f.key)
+		if name == "" {
+			continue
+		}
+		s := os.Getenv(name)
+		if s == "" {
+			continue
+		}
+		fv := v.Field(f.index)
+		switch fv.Kind() {
+		case reflect.String:
+			fv.SetString(s)
+		case reflect.Int:
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a number", name, s)
+			}
+			fv.SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("%s: %q is not a boolean", name, s)
+			}
+			fv.SetBool(b)
+		case reflect.Slice:
+			var list []string
+			for _, item := range strings.Split(s, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					list = append(list, item)
+				}
+			}
+			fv.Set(reflect.ValueOf(list))
+		}
+		c.setOrigin(f.key, "env "+name)
+	}
+	return nil
+}
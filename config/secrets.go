@@ -0,0 +1,456 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Secrets can be given as plain values, or indirectly:
+//
+//   - <key>_file: the secret is the content of the file (Docker/Kubernetes
+//     secrets), without the trailing newline;
+//   - <key>_command: the secret is the standard output of the command, e.g.
+//     ["pass", "show", "eusurvey/web"];
+//   - the encrypted secrets_file, unlocked with a passphrase.
+//
+// The EUSURVEYMGR_<KEY> environment variable still takes precedence. For
+// api_tokens, the secret holds one token per line (or comma-separated).
+//
+// The password of a ui_users entry has the same sources (password_file,
+// password_command, secrets file key ui_users.<username>.password). Hook
+// headers come from headers_file, headers_command or the secrets file key
+// hooks.<name>.headers as "Name: value" lines, added to the plain headers.
+var secretKeys = []string{"web_password", "db_password", "db_admin_password", "pseudonym_key", "api_tokens"}
+
+// PassphraseFunc returns the passphrase of the encrypted secrets file at
+// path. The default reads EUSURVEYMGR_SECRETS_PASSPHRASE; the CLI replaces it
+// to prompt on a terminal.
+var PassphraseFunc = func(path string) (string, error) {
+	if p := os.Getenv("EUSURVEYMGR_SECRETS_PASSPHRASE"); p != "" {
+		return p, nil
+	}
+	return "", fmt.Errorf("set EUSURVEYMGR_SECRETS_PASSPHRASE to unlock %s", path)
+}
+
+// Decrypted secrets files and their passphrases by path, so the passphrase
+// is asked for once per process (serve reloads the config for every job).
+var (
+	secretsMu    sync.Mutex
+	secretsCache = make(map[string]map[string]string)
+	passphrases  = make(map[string]string)
+)
+
+// Passphrase returns the passphrase of the secrets file at path, asking
+// PassphraseFunc the first time.
+func Passphrase(path string) (string, error) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	return passphrase(path)
+}
+
+func passphrase(path string) (string, error) {
+	if p, ok := passphrases[path]; ok {
+		return p, nil
+	}
+	p, err := PassphraseFunc(path)
+	if err != nil {
+		return "", err
+	}
+	passphrases[path] = p
+	return p, nil
+}
+
+// resolveSecrets fills the secret fields from their _file and _command keys
+// and the secrets file. Secrets set from the environment are left alone.
+func resolveSecrets(c *Configuration) error {
+	for _, key := range secretKeys {
+		if strings.HasPrefix(c.Origin(key), "env ") {
+			continue
+		}
+		file, command := c.secretSource(key)
+		plain := c.secretSet(key)
+		if strings.HasPrefix(c.Origin(key+"_file"), "env ") || strings.HasPrefix(c.Origin(key+"_command"), "env ") {
+			// An indirection from the environment replaces the config value.
+			plain = false
+		}
+		value, origin, err := readSecret(key, plain, file, command, c.SecretsFile)
+		if err != nil {
+			return err
+		}
+		if origin == "" {
+			continue
+		}
+		c.setSecret(key, value)
+		c.setOrigin(key, origin)
+	}
+
+	for i := range c.UIUsers {
+		u := &c.UIUsers[i]
+		key := "ui_users." + u.Username + ".password"
+		value, origin, err := readSecret(key, u.Password != "", u.PasswordFile, u.PasswordCommand, c.SecretsFile)
+		if err != nil {
+			return err
+		}
+		if origin != "" {
+			u.Password = value
+			c.setOrigin(key, origin)
+		}
+	}
+
+	// Headers from a source are added to the plain ones, so only the
+	// secret ones need to be kept out of the config.
+	for i := range c.Hooks {
+		h := &c.Hooks[i]
+		key := "hooks." + h.Name + ".headers"
+		value, origin, err := readSecret(key, false, h.HeadersFile, h.HeadersCommand, c.SecretsFile)
+		if err != nil {
+			return err
+		}
+		if origin == "" {
+			continue
+		}
+		headers, err := parseHeaders(value)
+		if err != nil {
+			return fmt.Errorf("%s (%s): %w", key, origin, err)
+		}
+		merged := make(map[string]string, len(h.Headers)+len(headers))
+		maps.Copy(merged, h.Headers)
+		maps.Copy(merged, headers)
+		h.Headers = merged
+		c.setOrigin(key, origin)
+	}
+	return nil
+}
+
+// readSecret reads secret key from its file or command, or from the secrets
+// file if neither is given and plain (the value itself) is not set. origin
+// names the source, and is empty when there is none.
+func readSecret(key string, plain bool, file string, command []string, secretsFile string) (value, origin string, err error) {
+	if err := singleSource(key, plain, file, command); err != nil {
+		return "", "", err
+	}
+
+	switch {
+	case file != "":
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", "", fmt.Errorf("%s_file: %w", key, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), key + "_file " + file, nil
+	case len(command) > 0:
+		var stdout, stderr bytes.Buffer
+		cmd := exec.Command(command[0], command[1:]...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, &stdout, &stderr
+		if err := cmd.Run(); err != nil {
+			return "", "", fmt.Errorf("%s_command: %w: %s", key, err, strings.TrimSpace(stderr.String()))
+		}
+		return strings.TrimRight(stdout.String(), "\r\n"), key + "_command " + command[0], nil
+	case !plain && secretsFile != "":
+		secrets, err := unlockSecretsFile(secretsFile)
+		if err != nil {
+			return "", "", err
+		}
+		if v, ok := secrets[key]; ok {
+			return v, "secrets_file " + secretsFile, nil
+		}
+	}
+	return "", "", nil
+}
+
+// singleSource checks that at most one of the plain value, file and command
+// of secret key is given.
+func singleSource(key string, plain bool, file string, command []string) error {
+	set := 0
+	for _, given := range []bool{plain, file != "", len(command) > 0} {
+		if given {
+			set++
+		}
+	}
+	if set > 1 {
+		return fmt.Errorf("set only one of %s, %s_file and %s_command", key, key, key)
+	}
+	return nil
+}
+
+// checkSecretSources checks the secret sources of an unresolved
+// configuration (see ReadProfile) without reading them: one source per
+// secret, existing _file files, _command programs found in PATH and an
+// existing secrets_file. Nothing is run and the secrets file stays locked.
+func (c *Configuration) checkSecretSources() []error {
+	var errs []error
+	check := func(key string, plain bool, file string, command []string) {
+		if err := singleSource(key, plain, file, command); err != nil {
+			errs = append(errs, err)
+			return
+		}
+		if file != "" {
+			if _, err := os.Stat(file); err != nil {
+				errs = append(errs, fmt.Errorf("%s_file: %w", key, err))
+			}
+		}
+		if len(command) > 0 {
+			if _, err := exec.LookPath(command[0]); err != nil {
+				errs = append(errs, fmt.Errorf("%s_command: %w", key, err))
+			}
+		}
+	}
+	for _, key := range secretKeys {
+		if strings.HasPrefix(c.Origin(key), "env ") {
+			continue
+		}
+		file, command := c.secretSource(key)
+		plain := c.secretSet(key)
+		if strings.HasPrefix(c.Origin(key+"_file"), "env ") || strings.HasPrefix(c.Origin(key+"_command"), "env ") {
+			plain = false
+		}
+		check(key, plain, file, command)
+	}
+	for _, u := range c.UIUsers {
+		check("ui_users."+u.Username+".password", u.Password != "", u.PasswordFile, u.PasswordCommand)
+	}
+	for _, h := range c.Hooks {
+		check("hooks."+h.Name+".headers", false, h.HeadersFile, h.HeadersCommand)
+	}
+	if c.SecretsFile != "" {
+		if _, err := os.Stat(c.SecretsFile); err != nil {
+			errs = append(errs, fmt.Errorf("secrets_file: %w", err))
+		}
+	}
+	return errs
+}
+
+// secretGiven reports whether secret key has a value or a source, before or
+// after resolution; the secrets file may hold any secret.
+func (c *Configuration) secretGiven(key string) bool {
+	file, command := c.secretSource(key)
+	return c.secretSet(key) || file != "" || len(command) > 0 || c.SecretsFile != ""
+}
+
+// parseHeaders parses HTTP headers given as "Name: value" lines.
+func parseHeaders(s string) (map[string]string, error) {
+	headers := make(map[string]string)
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if name = strings.TrimSpace(name); !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("line %d is not \"Name: value\"", i+1)
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers, nil
+}
+
+func (c *Configuration) secretSource(key string) (string, []string) {
+	switch key {
+	case "web_password":
+		return c.WebPasswordFile, c.WebPasswordCommand
+	case "db_password":
+		return c.DBPasswordFile, c.DBPasswordCommand
+	case "db_admin_password":
+		return c.DBAdminPasswordFile, c.DBAdminPasswordCommand
+	case "pseudonym_key":
+		return c.PseudonymKeyFile, c.PseudonymKeyCommand
+	case "api_tokens":
+		return c.APITokensFile, c.APITokensCommand
+	}
+	return "", nil
+}
+
+func (c *Configuration) secretSet(key string) bool {
+	switch key {
+	case "web_password":
+		return c.WebPassword != ""
+	case "db_password":
+		return c.DBPassword != ""
+	case "db_admin_password":
+		return c.DBAdminPassword != ""
+	case "pseudonym_key":
+		return c.PseudonymKey != ""
+	case "api_tokens":
+		return len(c.APITokens) > 0
+	}
+	return false
+}
+
+func (c *Configuration) setSecret(key, value string) {
+	switch key {
+	case "web_password":
+		c.WebPassword = value
+	case "db_password":
+		c.DBPassword = value
+	case "db_admin_password":
+		c.DBAdminPassword = value
+	case "pseudonym_key":
+		c.PseudonymKey = value
+	case "api_tokens":
+		c.APITokens = strings.FieldsFunc(value, func(r rune) bool {
+			return r == '\n' || r == '\r' || r == ',' || r == ' '
+		})
+	}
+}
+
+func unlockSecretsFile(path string) (map[string]string, error) {
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if s, ok := secretsCache[path]; ok {
+		return s, nil
+	}
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		// Not created yet: no secrets, and no passphrase to ask for.
+		return map[string]string{}, nil
+	}
+	pass, err := passphrase(path)
+	if err != nil {
+		return nil, err
+	}
+	s, err := ReadSecretsFile(path, pass)
+	if err != nil {
+		delete(passphrases, path)
+		return nil, err
+	}
+	secretsCache[path] = s
+	return s, nil
+}
+
+// SecretKeys returns the top-level config keys that can be stored in a
+// secrets file; see IsSecretKey for the others.
+func SecretKeys() []string {
+	return secretKeys
+}
+
+// IsSecretKey reports whether key can be stored in a secrets file: one of
+// SecretKeys, ui_users.<username>.password or hooks.<name>.headers.
+func IsSecretKey(key string) bool {
+	if slices.Contains(secretKeys, key) {
+		return true
+	}
+	parts := strings.Split(key, ".")
+	if len(parts) != 3 || parts[1] == "" {
+		return false
+	}
+	return (parts[0] == "ui_users" && parts[2] == "password") || (parts[0] == "hooks" && parts[2] == "headers")
+}
+
+// secretsFile is the on-disk form of an encrypted secrets file: a JSON
+// object of key/value pairs sealed with AES-256-GCM under a key derived from
+// the passphrase with PBKDF2-SHA256.
+type secretsFile struct {
+	Version    int    `json:"version"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Data       []byte `json:"data"`
+}
+
+const secretsIterations = 600000
+
+// ReadSecretsFile decrypts the secrets file at path. A missing file yields
+// no secrets.
+func ReadSecretsFile(path, passphrase string) (map[string]string, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets file: %w", err)
+	}
+	var f secretsFile
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("reading secrets file %s: %w", path, err)
+	}
+	if f.Version != 1 {
+		return nil, fmt.Errorf("secrets file %s: unsupported version %d", path, f.Version)
+	}
+	gcm, err := secretsCipher(passphrase, f.Salt, f.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	plain, err := gcm.Open(nil, f.Nonce, f.Data, nil)
+	if err != nil {
+		return nil, fmt.Errorf("secrets file %s: wrong passphrase or corrupted file", path)
+	}
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("secrets file %s: %w", path, err)
+	}
+	return secrets, nil
+}
+
+// WriteSecretsFile encrypts secrets to path (mode 0600) with a fresh salt
+// and nonce, replacing the file atomically.
+func WriteSecretsFile(path, passphrase string, secrets map[string]string) error {
+	if passphrase == "" {
+		return fmt.Errorf("empty passphrase")
+	}
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+	f := secretsFile{Version: 1, Iterations: secretsIterations, Salt: make([]byte, 16)}
+	if _, err := rand.Read(f.Salt); err != nil {
+		return err
+	}
+	gcm, err := secretsCipher(passphrase, f.Salt, f.Iterations)
+	if err != nil {
+		return err
+	}
+	f.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(f.Nonce); err != nil {
+		return err
+	}
+	f.Data = gcm.Seal(nil, f.Nonce, plain, nil)
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".secrets-*")
+	if err != nil {
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+	secretsMu.Lock()
+	secretsCache[path] = secrets
+	passphrases[path] = passphrase
+	secretsMu.Unlock()
+	return nil
+}
+
+func secretsCipher(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
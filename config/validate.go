@@ -0,0 +1,196 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"eusurveymgr/schedule"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ValidateFile checks a config file strictly: unknown keys (at any level, in
+// the top level and in every profile) are errors, and the top level and each
+// profile must pass Validate. Secrets are not resolved: their sources are
+// checked without running commands or unlocking the secrets file.
+func ValidateFile(filePath string) error {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return err
+	}
+	var c Configuration
+	if err := decodeStrict(content, &c); err != nil {
+		return err
+	}
+	var errs []error
+	for _, name := range c.ProfileNames() {
+		var p Configuration
+		if err := decodeStrict(c.Profiles[name], &p); err != nil {
+			errs = append(errs, fmt.Errorf("profile %q: %w", name, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	// With profiles the top level only provides their defaults and need not
+	// be complete on its own.
+	names := c.ProfileNames()
+	if len(names) == 0 {
+		names = []string{""}
+	}
+	for _, name := range names {
+		loaded, err := ReadProfile(filePath, name)
+		if err == nil {
+			err = errors.Join(append(loaded.checkSecretSources(), loaded.Validate())...)
+		}
+		if err == nil {
+			continue
+		}
+		if name == "" {
+			errs = append(errs, err)
+			continue
+		}
+		for _, line := range strings.Split(err.Error(), "\n") {
+			errs = append(errs, fmt.Errorf("profile %q: %s", name, line))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func decodeStrict(data []byte, v any) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if dec.More() {
+		return fmt.Errorf("unexpected data after the JSON object")
+	}
+	return nil
+}
+
+// ValidateJob, if set, checks the command line of each job. The cmd package
+// sets it to refuse commands that never return.
+var ValidateJob func(command []string) error
+
+// Validate checks that the values make sense and returns all problems found.
+func (c *Configuration) Validate() error {
+	var errs []error
+	add := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.BaseURL == "" {
+		add("base_url is required")
+	} else if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		add("base_url: %q is not an http(s) URL", c.BaseURL)
+	}
+	if (c.WebPassword != "" || c.WebPasswordFile != "" || len(c.WebPasswordCommand) > 0) && c.WebUser == "" {
+		add("web_password is set but web_user is empty")
+	}
+	if c.DBPort < 1 || c.DBPort > 65535 {
+		add("db_port: %d is not a valid port", c.DBPort)
+	}
+	if c.DBHost != "" && c.DBName == "" {
+		add("db_name is required with db_host")
+	}
+	if c.TimeoutSeconds < 1 {
+		add("timeout_seconds: must be positive, got %d", c.TimeoutSeconds)
+	}
+	if c.APIDailyBudget < 0 {
+		add("api_daily_budget: must not be negative, got %d", c.APIDailyBudget)
+	}
+	for _, dir := range []struct{ key, path string }{{"output_dir", c.OutputDir}, {"state_dir", c.StateDir}} {
+		if info, err := os.Stat(dir.path); err == nil && !info.IsDir() {
+			add("%s: %s is not a directory", dir.key, dir.path)
+		}
+	}
+	for _, f := range []struct{ key, path string }{{"pdf_logo", c.PDFLogo}, {"pdf_font", c.PDFFont}} {
+		if f.path == "" {
+			continue
+		}
+		if info, err := os.Stat(f.path); err != nil {
+			add("%s: %v", f.key, err)
+		} else if info.IsDir() {
+			add("%s: %s is a directory", f.key, f.path)
+		}
+	}
+	switch ext := strings.ToLower(filepath.Ext(c.PDFLogo)); {
+	case c.PDFLogo == "", ext == ".png", ext == ".jpg", ext == ".jpeg":
+	default:
+		add("pdf_logo: %s is not a PNG or JPEG file", c.PDFLogo)
+	}
+	switch c.PseudonymMode {
+	case "", "hash", "drop":
+	default:
+		add("pseudonym_mode: %q is not \"hash\" or \"drop\"", c.PseudonymMode)
+	}
+	if c.PseudonymMode == "hash" && !c.secretGiven("pseudonym_key") {
+		add("pseudonym_key is required for pseudonym_mode \"hash\"")
+	}
+	for i, t := range c.APITokens {
+		if t == "" {
+			add("api_tokens[%d] is empty", i)
+		}
+	}
+
+	users := make(map[string]bool)
+	for i, u := range c.UIUsers {
+		switch {
+		case u.Username == "" || (u.Password == "" && u.PasswordFile == "" && len(u.PasswordCommand) == 0 && c.SecretsFile == ""):
+			add("ui_users[%d]: username and password are required", i)
+		case users[u.Username]:
+			add("ui_users[%d]: duplicate username %q", i, u.Username)
+		}
+		users[u.Username] = true
+	}
+
+	hooks := make(map[string]bool)
+	for i, h := range c.Hooks {
+		if h.Name == "" {
+			add("hooks[%d]: name is required", i)
+		} else if hooks[h.Name] {
+			add("hooks[%d]: duplicate name %q", i, h.Name)
+		}
+		hooks[h.Name] = true
+		if h.Survey <= 0 {
+			add("hooks[%d]: survey is required", i)
+		}
+		switch {
+		case (h.URL == "") == (len(h.Command) == 0):
+			add("hooks[%d]: exactly one of url and command is required", i)
+		case h.URL != "":
+			if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				add("hooks[%d]: url %q is not an http(s) URL", i, h.URL)
+			}
+		}
+		if h.MaxAttempts < 0 || h.TimeoutSeconds < 0 {
+			add("hooks[%d]: max_attempts and timeout_seconds must not be negative", i)
+		}
+	}
+
+	jobs := make(map[string]bool)
+	for i, j := range c.Jobs {
+		if j.Name == "" {
+			add("jobs[%d]: name is required", i)
+		} else if jobs[j.Name] {
+			add("jobs[%d]: duplicate name %q", i, j.Name)
+		}
+		jobs[j.Name] = true
+		if len(j.Command) == 0 {
+			add("jobs[%d]: command is required", i)
+		} else if ValidateJob != nil {
+			if err := ValidateJob(j.Command); err != nil {
+				add("jobs[%d]: %v", i, err)
+			}
+		}
+		if _, err := schedule.Parse(j.Schedule); err != nil {
+			add("jobs[%d]: %v", i, err)
+		}
+	}
+	return errors.Join(errs...)
+}
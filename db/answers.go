@@ -13,17 +13,20 @@ type AnswerSetRow struct {
 	Email       sql.NullString
 }
 
-func ListAnswerSets(db *sql.DB, surveyID int64) ([]AnswerSetRow, error) {
-	// PA_ID=0 has two rows per answer set: name (first inserted) and email (second).
-	// We use MIN/MAX on ANSWER_ID to reliably distinguish them.
-	query := `
+// answerSetQuery selects answer sets with their identity. PA_ID=0 has two
+// rows per answer set: name (first inserted) and email (second). We use
+// MIN/MAX on ANSWER_ID to reliably distinguish them.
+const answerSetQuery = `
 		SELECT a_set.ANSWER_SET_ID, a_set.UNIQUECODE, a_set.ANSWER_SET_DATE,
 		       a_name.VALUE as name, a_email.VALUE as email
 		FROM ANSWERS_SET a_set
 		LEFT JOIN ANSWERS a_name ON a_name.AS_ID = a_set.ANSWER_SET_ID
 		    AND a_name.ANSWER_ID = (SELECT MIN(ANSWER_ID) FROM ANSWERS WHERE AS_ID = a_set.ANSWER_SET_ID AND PA_ID = 0)
 		LEFT JOIN ANSWERS a_email ON a_email.AS_ID = a_set.ANSWER_SET_ID
-		    AND a_email.ANSWER_ID = (SELECT MAX(ANSWER_ID) FROM ANSWERS WHERE AS_ID = a_set.ANSWER_SET_ID AND PA_ID = 0)
+		    AND a_email.ANSWER_ID = (SELECT MAX(ANSWER_ID) FROM ANSWERS WHERE AS_ID = a_set.ANSWER_SET_ID AND PA_ID = 0)`
+
+func ListAnswerSets(db *sql.DB, surveyID int64) ([]AnswerSetRow, error) {
+	query := answerSetQuery + `
 		WHERE a_set.SURVEY_ID = ?
 		ORDER BY a_set.ANSWER_SET_DATE DESC`
 
@@ -31,6 +34,25 @@ func ListAnswerSets(db *sql.DB, surveyID int64) ([]AnswerSetRow, error) {
 	if err != nil {
 		return nil, fmt.Errorf("listing answer sets: %w", err)
 	}
+	return scanAnswerSets(rows)
+}
+
+// ListAnswerSetsAfter returns the answer sets of a survey with an
+// ANSWER_SET_ID greater than afterID, oldest first. IDs are assigned in
+// insertion order, so this is how pollers fetch new submissions.
+func ListAnswerSetsAfter(db *sql.DB, surveyID, afterID int64) ([]AnswerSetRow, error) {
+	query := answerSetQuery + `
+		WHERE a_set.SURVEY_ID = ? AND a_set.ANSWER_SET_ID > ?
+		ORDER BY a_set.ANSWER_SET_ID`
+
+	rows, err := db.Query(query, surveyID, afterID)
+	if err != nil {
+		return nil, fmt.Errorf("listing new answer sets: %w", err)
+	}
+	return scanAnswerSets(rows)
+}
+
+func scanAnswerSets(rows *sql.Rows) ([]AnswerSetRow, error) {
 	defer rows.Close()
 
 	var answers []AnswerSetRow
@@ -48,11 +70,16 @@ type ResponseRow struct {
 	PA_ID    int
 	Question sql.NullString
 	Value    sql.NullString
+	// QuestionUID is the element answered; PA_UID the chosen option, if
+	// any (Question is its title).
+	QuestionUID string
+	PA_UID      sql.NullString
 }
 
 func GetResponses(db *sql.DB, answerSetID int64) ([]ResponseRow, error) {
 	query := `
-		SELECT a.PA_ID, e.ETITLE as question, a.VALUE
+		SELECT a.PA_ID, e.ETITLE as question, a.VALUE,
+		       COALESCE(a.QUESTION_UID,''), a.PA_UID
 		FROM ANSWERS a
 		LEFT JOIN ELEMENTS e ON e.ELEM_UID = a.PA_UID
 		WHERE a.AS_ID = ?
@@ -67,7 +94,7 @@ func GetResponses(db *sql.DB, answerSetID int64) ([]ResponseRow, error) {
 	var responses []ResponseRow
 	for rows.Next() {
 		var r ResponseRow
-		if err := rows.Scan(&r.PA_ID, &r.Question, &r.Value); err != nil {
+		if err := rows.Scan(&r.PA_ID, &r.Question, &r.Value, &r.QuestionUID, &r.PA_UID); err != nil {
 			return nil, fmt.Errorf("scanning response row: %w", err)
 		}
 		responses = append(responses, r)
@@ -96,4 +123,66 @@ func LookupUniqueCode(db *sql.DB, email string, surveyID int64) (int64, string,
 		return 0, "", fmt.Errorf("looking up uniquecode: %w", err)
 	}
 	return answerSetID, uniqueCode, nil
-}
\ No newline at end of file
+}
+
+type SubjectAnswerSetRow struct {
+	AnswerSetID int64
+	SurveyID    int64
+	Alias       string
+	Title       string
+	UniqueCode  string
+	Date        sql.NullString
+}
+
+// FindAnswerSetsByEmail returns every answer set, across all surveys, whose
+// identity section (PA_ID=0) contains the given email address.
+func FindAnswerSetsByEmail(db *sql.DB, email string) ([]SubjectAnswerSetRow, error) {
+	query := `
+		SELECT DISTINCT a_set.ANSWER_SET_ID, a_set.SURVEY_ID,
+		       COALESCE(s.SURVEYNAME,''), COALESCE(s.TITLE,''),
+		       a_set.UNIQUECODE, a_set.ANSWER_SET_DATE
+		FROM ANSWERS_SET a_set
+		JOIN ANSWERS a ON a.AS_ID = a_set.ANSWER_SET_ID
+		JOIN SURVEYS s ON s.SURVEY_ID = a_set.SURVEY_ID
+		WHERE a.PA_ID = 0
+		  AND a.VALUE = ?
+		ORDER BY a_set.ANSWER_SET_DATE`
+
+	rows, err := db.Query(query, email)
+	if err != nil {
+		return nil, fmt.Errorf("finding answer sets by email: %w", err)
+	}
+	defer rows.Close()
+
+	var sets []SubjectAnswerSetRow
+	for rows.Next() {
+		var s SubjectAnswerSetRow
+		if err := rows.Scan(&s.AnswerSetID, &s.SurveyID, &s.Alias, &s.Title, &s.UniqueCode, &s.Date); err != nil {
+			return nil, fmt.Errorf("scanning answer set row: %w", err)
+		}
+		sets = append(sets, s)
+	}
+	return sets, rows.Err()
+}
+
+// GetAnswerSetByCode returns the answer set with the given UNIQUECODE. If
+// there is none, the error wraps sql.ErrNoRows.
+func GetAnswerSetByCode(db *sql.DB, uniqueCode string) (*SubjectAnswerSetRow, error) {
+	query := `
+		SELECT a_set.ANSWER_SET_ID, a_set.SURVEY_ID,
+		       COALESCE(s.SURVEYNAME,''), COALESCE(s.TITLE,''),
+		       a_set.UNIQUECODE, a_set.ANSWER_SET_DATE
+		FROM ANSWERS_SET a_set
+		JOIN SURVEYS s ON s.SURVEY_ID = a_set.SURVEY_ID
+		WHERE a_set.UNIQUECODE = ?`
+
+	var a SubjectAnswerSetRow
+	err := db.QueryRow(query, uniqueCode).Scan(&a.AnswerSetID, &a.SurveyID, &a.Alias, &a.Title, &a.UniqueCode, &a.Date)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("no answer set with uniquecode %q: %w", uniqueCode, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("getting answer set by uniquecode: %w", err)
+	}
+	return &a, nil
+}
@@ -0,0 +1,155 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+type ElementRow struct {
+	ElementID int64
+	UID       string
+	Title     sql.NullString
+	Type      string
+	Optional  bool
+	Position  int
+}
+
+// ListElements returns the top-level elements (questions, sections, text
+// blocks) of a survey in display order.
+func ListElements(db *sql.DB, surveyID int64) ([]ElementRow, error) {
+	query := `
+		SELECT e.ID, COALESCE(e.ELEM_UID,''), e.ETITLE, COALESCE(e.ETYPE,''),
+		       COALESCE(e.QOPTIONAL, 1), se.elements_ORDER
+		FROM SURVEYS_ELEMENTS se
+		JOIN ELEMENTS e ON e.ID = se.elements_ID
+		WHERE se.SURVEYS_SURVEY_ID = ?
+		ORDER BY se.elements_ORDER`
+
+	rows, err := db.Query(query, surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("listing elements: %w", err)
+	}
+	defer rows.Close()
+
+	var elements []ElementRow
+	for rows.Next() {
+		var e ElementRow
+		if err := rows.Scan(&e.ElementID, &e.UID, &e.Title, &e.Type, &e.Optional, &e.Position); err != nil {
+			return nil, fmt.Errorf("scanning element row: %w", err)
+		}
+		elements = append(elements, e)
+	}
+	return elements, rows.Err()
+}
+
+type OptionRow struct {
+	QuestionUID string
+	UID         string
+	Title       sql.NullString
+}
+
+// ListPossibleAnswers returns the answer options of every choice question in
+// a survey. Options are linked to their question through ELEMENTS_ELEMENTS.
+func ListPossibleAnswers(db *sql.DB, surveyID int64) ([]OptionRow, error) {
+	query := `
+		SELECT COALESCE(q.ELEM_UID,''), COALESCE(pa.ELEM_UID,''), pa.ETITLE
+		FROM SURVEYS_ELEMENTS se
+		JOIN ELEMENTS q ON q.ID = se.elements_ID
+		JOIN ELEMENTS_ELEMENTS ee ON ee.ELEMENTS_ID = q.ID
+		JOIN ELEMENTS pa ON pa.ID = ee.possibleAnswers_ID
+		WHERE se.SURVEYS_SURVEY_ID = ?
+		ORDER BY se.elements_ORDER, pa.ID`
+
+	rows, err := db.Query(query, surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("listing possible answers: %w", err)
+	}
+	defer rows.Close()
+
+	var options []OptionRow
+	for rows.Next() {
+		var o OptionRow
+		if err := rows.Scan(&o.QuestionUID, &o.UID, &o.Title); err != nil {
+			return nil, fmt.Errorf("scanning possible answer row: %w", err)
+		}
+		options = append(options, o)
+	}
+	return options, rows.Err()
+}
+
+type MatrixRow struct {
+	MatrixUID string
+	UID       string
+	Title     sql.NullString
+}
+
+// ListMatrixRows returns the rows of the matrix elements of a survey. A
+// matrix keeps its rows and its columns alike in ELEMENTS_ELEMENTS; the rows
+// are the children answered as questions (ANSWERS.QUESTION_UID), the columns
+// the options chosen for them (PA_UID). A row nobody answered is not listed.
+func ListMatrixRows(db *sql.DB, surveyID int64) ([]MatrixRow, error) {
+	query := `
+		SELECT COALESCE(m.ELEM_UID,''), COALESCE(r.ELEM_UID,''), r.ETITLE
+		FROM SURVEYS_ELEMENTS se
+		JOIN ELEMENTS m ON m.ID = se.elements_ID
+		JOIN ELEMENTS_ELEMENTS ee ON ee.ELEMENTS_ID = m.ID
+		JOIN ELEMENTS r ON r.ID = ee.possibleAnswers_ID
+		WHERE se.SURVEYS_SURVEY_ID = ? AND UPPER(m.ETYPE) = 'MATRIX'
+		  AND EXISTS (
+		      SELECT 1 FROM ANSWERS a
+		      JOIN ANSWERS_SET a_set ON a_set.ANSWER_SET_ID = a.AS_ID
+		      WHERE a_set.SURVEY_ID = ? AND a.QUESTION_UID = r.ELEM_UID
+		  )
+		ORDER BY se.elements_ORDER, r.ID`
+
+	rows, err := db.Query(query, surveyID, surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("listing matrix rows: %w", err)
+	}
+	defer rows.Close()
+
+	var matrixRows []MatrixRow
+	for rows.Next() {
+		var m MatrixRow
+		if err := rows.Scan(&m.MatrixUID, &m.UID, &m.Title); err != nil {
+			return nil, fmt.Errorf("scanning matrix row: %w", err)
+		}
+		matrixRows = append(matrixRows, m)
+	}
+	return matrixRows, rows.Err()
+}
+
+type AnswerRow struct {
+	AnswerSetID int64
+	QuestionUID string
+	PA_ID       int
+	PA_UID      sql.NullString
+	Value       sql.NullString
+}
+
+// ListSurveyAnswers returns every answer of every answer set in a survey,
+// grouped by answer set in insertion order.
+func ListSurveyAnswers(db *sql.DB, surveyID int64) ([]AnswerRow, error) {
+	query := `
+		SELECT a.AS_ID, COALESCE(a.QUESTION_UID,''), a.PA_ID, a.PA_UID, a.VALUE
+		FROM ANSWERS a
+		JOIN ANSWERS_SET a_set ON a_set.ANSWER_SET_ID = a.AS_ID
+		WHERE a_set.SURVEY_ID = ?
+		ORDER BY a.AS_ID, a.ANSWER_ID`
+
+	rows, err := db.Query(query, surveyID)
+	if err != nil {
+		return nil, fmt.Errorf("listing survey answers: %w", err)
+	}
+	defer rows.Close()
+
+	var answers []AnswerRow
+	for rows.Next() {
+		var a AnswerRow
+		if err := rows.Scan(&a.AnswerSetID, &a.QuestionUID, &a.PA_ID, &a.PA_UID, &a.Value); err != nil {
+			return nil, fmt.Errorf("scanning answer row: %w", err)
+		}
+		answers = append(answers, a)
+	}
+	return answers, rows.Err()
+}
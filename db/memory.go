@@ -0,0 +1,362 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Fixture is survey data for a MemoryRepository, usually loaded from JSON.
+type Fixture struct {
+	Surveys []FixtureSurvey `json:"surveys"`
+}
+
+type FixtureSurvey struct {
+	ID         int64              `json:"id"`
+	UID        string             `json:"uid"`
+	Alias      string             `json:"alias"`
+	Title      string             `json:"title"`
+	Created    string             `json:"created"`
+	Published  bool               `json:"published"`
+	Elements   []FixtureElement   `json:"elements"`
+	AnswerSets []FixtureAnswerSet `json:"answer_sets"`
+}
+
+// FixtureElement is a top-level survey element. The Options of a MATRIX
+// are its columns and Rows its questions, all children in ELEMENTS_ELEMENTS.
+type FixtureElement struct {
+	UID      string          `json:"uid"`
+	Title    string          `json:"title"`
+	Type     string          `json:"type"`
+	Optional bool            `json:"optional"`
+	Options  []FixtureOption `json:"options,omitempty"`
+	Rows     []FixtureOption `json:"rows,omitempty"`
+}
+
+type FixtureOption struct {
+	UID   string `json:"uid"`
+	Title string `json:"title"`
+}
+
+type FixtureAnswerSet struct {
+	ID         int64           `json:"id"`
+	UniqueCode string          `json:"uniquecode"`
+	Date       string          `json:"date"`
+	Answers    []FixtureAnswer `json:"answers"`
+}
+
+// FixtureAnswer is one ANSWERS row. Identity answers (name, then email)
+// have PA_ID 0, as in the database.
+type FixtureAnswer struct {
+	QuestionUID string `json:"question_uid"`
+	PA_ID       int    `json:"pa_id"`
+	PA_UID      string `json:"pa_uid,omitempty"`
+	Value       string `json:"value"`
+}
+
+// LoadFixture reads a JSON fixture file.
+func LoadFixture(path string) (*Fixture, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(content, &f); err != nil {
+		return nil, fmt.Errorf("parsing fixture %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+// MemoryRepository is a SurveyRepository over a Fixture. It reproduces the
+// ordering and identity rules of the SQL queries.
+type MemoryRepository struct {
+	fixture *Fixture
+	titles  map[string]string
+}
+
+func NewMemoryRepository(f *Fixture) *MemoryRepository {
+	r := &MemoryRepository{fixture: f, titles: make(map[string]string)}
+	for _, s := range f.Surveys {
+		for _, e := range s.Elements {
+			r.titles[e.UID] = e.Title
+			for _, o := range slices.Concat(e.Options, e.Rows) {
+				r.titles[o.UID] = o.Title
+			}
+		}
+	}
+	return r
+}
+
+func (r *MemoryRepository) survey(surveyID int64) *FixtureSurvey {
+	for i := range r.fixture.Surveys {
+		if r.fixture.Surveys[i].ID == surveyID {
+			return &r.fixture.Surveys[i]
+		}
+	}
+	return nil
+}
+
+func (r *MemoryRepository) ListSurveys() ([]SurveyRow, error) {
+	// Keep only the latest version (max ID) per UID, as ListSurveys does.
+	latest := make(map[string]FixtureSurvey)
+	for _, s := range r.fixture.Surveys {
+		if cur, ok := latest[s.UID]; !ok || s.ID > cur.ID {
+			latest[s.UID] = s
+		}
+	}
+	var surveys []SurveyRow
+	for _, s := range latest {
+		surveys = append(surveys, SurveyRow{
+			SurveyID:   s.ID,
+			Title:      s.Title,
+			Alias:      s.Alias,
+			SurveyUID:  s.UID,
+			Created:    nullString(s.Created),
+			Published:  s.Published,
+			NumAnswers: len(s.AnswerSets),
+		})
+	}
+	sort.Slice(surveys, func(i, j int) bool {
+		return surveys[i].Created.String > surveys[j].Created.String
+	})
+	return surveys, nil
+}
+
+func (r *MemoryRepository) ListAnswerSets(surveyID int64) ([]AnswerSetRow, error) {
+	s := r.survey(surveyID)
+	if s == nil {
+		return nil, nil
+	}
+	var answers []AnswerSetRow
+	for _, as := range s.AnswerSets {
+		name, email := identity(as.Answers)
+		answers = append(answers, AnswerSetRow{
+			AnswerSetID: as.ID,
+			UniqueCode:  as.UniqueCode,
+			Date:        nullString(as.Date),
+			Name:        name,
+			Email:       email,
+		})
+	}
+	sort.SliceStable(answers, func(i, j int) bool {
+		return answers[i].Date.String > answers[j].Date.String
+	})
+	return answers, nil
+}
+
+func (r *MemoryRepository) ListAnswerSetsAfter(surveyID, afterID int64) ([]AnswerSetRow, error) {
+	all, err := r.ListAnswerSets(surveyID)
+	if err != nil {
+		return nil, err
+	}
+	var answers []AnswerSetRow
+	for _, a := range all {
+		if a.AnswerSetID > afterID {
+			answers = append(answers, a)
+		}
+	}
+	sort.Slice(answers, func(i, j int) bool { return answers[i].AnswerSetID < answers[j].AnswerSetID })
+	return answers, nil
+}
+
+func (r *MemoryRepository) answerSet(answerSetID int64) (*FixtureSurvey, *FixtureAnswerSet) {
+	for i := range r.fixture.Surveys {
+		s := &r.fixture.Surveys[i]
+		for j := range s.AnswerSets {
+			if s.AnswerSets[j].ID == answerSetID {
+				return s, &s.AnswerSets[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (r *MemoryRepository) GetResponses(answerSetID int64) ([]ResponseRow, error) {
+	_, as := r.answerSet(answerSetID)
+	if as == nil {
+		return nil, nil
+	}
+	var responses []ResponseRow
+	for _, a := range as.Answers {
+		row := ResponseRow{PA_ID: a.PA_ID, Value: sql.NullString{String: a.Value, Valid: true},
+			QuestionUID: a.QuestionUID, PA_UID: sql.NullString{String: a.PA_UID, Valid: a.PA_UID != ""}}
+		// GetResponses joins the title on PA_UID, so only choice answers
+		// carry a question title.
+		if title, ok := r.titles[a.PA_UID]; ok && a.PA_UID != "" {
+			row.Question = sql.NullString{String: title, Valid: true}
+		}
+		responses = append(responses, row)
+	}
+	sort.SliceStable(responses, func(i, j int) bool { return responses[i].PA_ID < responses[j].PA_ID })
+	return responses, nil
+}
+
+func (r *MemoryRepository) LookupUniqueCode(email string, surveyID int64) (int64, string, error) {
+	var found *FixtureAnswerSet
+	if s := r.survey(surveyID); s != nil {
+		for i, as := range s.AnswerSets {
+			if hasIdentity(as.Answers, email) && (found == nil || as.Date > found.Date) {
+				found = &s.AnswerSets[i]
+			}
+		}
+	}
+	if found == nil {
+		return 0, "", fmt.Errorf("no answer set found for email=%q survey=%d", email, surveyID)
+	}
+	return found.ID, found.UniqueCode, nil
+}
+
+func (r *MemoryRepository) FindAnswerSetsByEmail(email string) ([]SubjectAnswerSetRow, error) {
+	var sets []SubjectAnswerSetRow
+	for _, s := range r.fixture.Surveys {
+		for _, as := range s.AnswerSets {
+			if !hasIdentity(as.Answers, email) {
+				continue
+			}
+			sets = append(sets, SubjectAnswerSetRow{
+				AnswerSetID: as.ID,
+				SurveyID:    s.ID,
+				Alias:       s.Alias,
+				Title:       s.Title,
+				UniqueCode:  as.UniqueCode,
+				Date:        nullString(as.Date),
+			})
+		}
+	}
+	sort.SliceStable(sets, func(i, j int) bool { return sets[i].Date.String < sets[j].Date.String })
+	return sets, nil
+}
+
+func (r *MemoryRepository) GetAnswerSetByCode(uniqueCode string) (*SubjectAnswerSetRow, error) {
+	for _, s := range r.fixture.Surveys {
+		for _, as := range s.AnswerSets {
+			if as.UniqueCode == uniqueCode {
+				return &SubjectAnswerSetRow{
+					AnswerSetID: as.ID,
+					SurveyID:    s.ID,
+					Alias:       s.Alias,
+					Title:       s.Title,
+					UniqueCode:  as.UniqueCode,
+					Date:        nullString(as.Date),
+				}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no answer set with uniquecode %q: %w", uniqueCode, sql.ErrNoRows)
+}
+
+func (r *MemoryRepository) ListElements(surveyID int64) ([]ElementRow, error) {
+	s := r.survey(surveyID)
+	if s == nil {
+		return nil, nil
+	}
+	var elements []ElementRow
+	for i, e := range s.Elements {
+		elements = append(elements, ElementRow{
+			ElementID: int64(i + 1),
+			UID:       e.UID,
+			Title:     nullString(e.Title),
+			Type:      e.Type,
+			Optional:  e.Optional,
+			Position:  i,
+		})
+	}
+	return elements, nil
+}
+
+func (r *MemoryRepository) ListPossibleAnswers(surveyID int64) ([]OptionRow, error) {
+	s := r.survey(surveyID)
+	if s == nil {
+		return nil, nil
+	}
+	var options []OptionRow
+	for _, e := range s.Elements {
+		// ListPossibleAnswers returns every child, so matrix rows too.
+		for _, o := range slices.Concat(e.Options, e.Rows) {
+			options = append(options, OptionRow{QuestionUID: e.UID, UID: o.UID, Title: nullString(o.Title)})
+		}
+	}
+	return options, nil
+}
+
+func (r *MemoryRepository) ListMatrixRows(surveyID int64) ([]MatrixRow, error) {
+	s := r.survey(surveyID)
+	if s == nil {
+		return nil, nil
+	}
+	answered := make(map[string]bool)
+	for _, as := range s.AnswerSets {
+		for _, a := range as.Answers {
+			answered[a.QuestionUID] = true
+		}
+	}
+	var rows []MatrixRow
+	for _, e := range s.Elements {
+		if !strings.EqualFold(e.Type, "MATRIX") {
+			continue
+		}
+		for _, row := range e.Rows {
+			if answered[row.UID] {
+				rows = append(rows, MatrixRow{MatrixUID: e.UID, UID: row.UID, Title: nullString(row.Title)})
+			}
+		}
+	}
+	return rows, nil
+}
+
+func (r *MemoryRepository) ListSurveyAnswers(surveyID int64) ([]AnswerRow, error) {
+	s := r.survey(surveyID)
+	if s == nil {
+		return nil, nil
+	}
+	var answers []AnswerRow
+	for _, as := range s.AnswerSets {
+		for _, a := range as.Answers {
+			answers = append(answers, AnswerRow{
+				AnswerSetID: as.ID,
+				QuestionUID: a.QuestionUID,
+				PA_ID:       a.PA_ID,
+				PA_UID:      nullString(a.PA_UID),
+				Value:       sql.NullString{String: a.Value, Valid: true},
+			})
+		}
+	}
+	sort.SliceStable(answers, func(i, j int) bool { return answers[i].AnswerSetID < answers[j].AnswerSetID })
+	return answers, nil
+}
+
+func (r *MemoryRepository) Close() error {
+	return nil
+}
+
+// identity returns the name (first PA_ID=0 answer) and email (last PA_ID=0
+// answer) of an answer set, matching the MIN/MAX rule of ListAnswerSets.
+func identity(answers []FixtureAnswer) (name, email sql.NullString) {
+	for _, a := range answers {
+		if a.PA_ID != 0 {
+			continue
+		}
+		if !name.Valid {
+			name = sql.NullString{String: a.Value, Valid: true}
+		}
+		email = sql.NullString{String: a.Value, Valid: true}
+	}
+	return name, email
+}
+
+func hasIdentity(answers []FixtureAnswer, value string) bool {
+	for _, a := range answers {
+		if a.PA_ID == 0 && a.Value == value {
+			return true
+		}
+	}
+	return false
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
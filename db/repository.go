@@ -0,0 +1,100 @@
+package db
+
+import (
+	"database/sql"
+	"eusurveymgr/metrics"
+	"time"
+)
+
+// SurveyRepository is read access to EUSurvey survey data. SQLRepository
+// implements it over MySQL or a SQLite snapshot and MemoryRepository over
+// in-memory fixtures, so tools built on it can be tested without a database.
+type SurveyRepository interface {
+	ListSurveys() ([]SurveyRow, error)
+	ListAnswerSets(surveyID int64) ([]AnswerSetRow, error)
+	ListAnswerSetsAfter(surveyID, afterID int64) ([]AnswerSetRow, error)
+	GetResponses(answerSetID int64) ([]ResponseRow, error)
+	LookupUniqueCode(email string, surveyID int64) (int64, string, error)
+	FindAnswerSetsByEmail(email string) ([]SubjectAnswerSetRow, error)
+	GetAnswerSetByCode(uniqueCode string) (*SubjectAnswerSetRow, error)
+	ListElements(surveyID int64) ([]ElementRow, error)
+	ListPossibleAnswers(surveyID int64) ([]OptionRow, error)
+	ListMatrixRows(surveyID int64) ([]MatrixRow, error)
+	ListSurveyAnswers(surveyID int64) ([]AnswerRow, error)
+	Close() error
+}
+
+// SQLRepository runs the db package queries against a *sql.DB. The queries
+// are portable between MySQL and the SQLite snapshots.
+type SQLRepository struct {
+	DB *sql.DB
+}
+
+// observeQuery records the duration of a repository query.
+func observeQuery(query string, start time.Time) {
+	metrics.DBQueryDuration.Since(start, query)
+}
+
+// NewSQLRepository wraps an open connection pool. Close closes the pool.
+func NewSQLRepository(db *sql.DB) *SQLRepository {
+	return &SQLRepository{DB: db}
+}
+
+func (r *SQLRepository) ListSurveys() ([]SurveyRow, error) {
+	defer observeQuery("ListSurveys", time.Now())
+	return ListSurveys(r.DB)
+}
+
+func (r *SQLRepository) ListAnswerSets(surveyID int64) ([]AnswerSetRow, error) {
+	defer observeQuery("ListAnswerSets", time.Now())
+	return ListAnswerSets(r.DB, surveyID)
+}
+
+func (r *SQLRepository) ListAnswerSetsAfter(surveyID, afterID int64) ([]AnswerSetRow, error) {
+	defer observeQuery("ListAnswerSetsAfter", time.Now())
+	return ListAnswerSetsAfter(r.DB, surveyID, afterID)
+}
+
+func (r *SQLRepository) GetResponses(answerSetID int64) ([]ResponseRow, error) {
+	defer observeQuery("GetResponses", time.Now())
+	return GetResponses(r.DB, answerSetID)
+}
+
+func (r *SQLRepository) LookupUniqueCode(email string, surveyID int64) (int64, string, error) {
+	defer observeQuery("LookupUniqueCode", time.Now())
+	return LookupUniqueCode(r.DB, email, surveyID)
+}
+
+func (r *SQLRepository) FindAnswerSetsByEmail(email string) ([]SubjectAnswerSetRow, error) {
+	defer observeQuery("FindAnswerSetsByEmail", time.Now())
+	return FindAnswerSetsByEmail(r.DB, email)
+}
+
+func (r *SQLRepository) GetAnswerSetByCode(uniqueCode string) (*SubjectAnswerSetRow, error) {
+	defer observeQuery("GetAnswerSetByCode", time.Now())
+	return GetAnswerSetByCode(r.DB, uniqueCode)
+}
+
+func (r *SQLRepository) ListElements(surveyID int64) ([]ElementRow, error) {
+	defer observeQuery("ListElements", time.Now())
+	return ListElements(r.DB, surveyID)
+}
+
+func (r *SQLRepository) ListPossibleAnswers(surveyID int64) ([]OptionRow, error) {
+	defer observeQuery("ListPossibleAnswers", time.Now())
+	return ListPossibleAnswers(r.DB, surveyID)
+}
+
+func (r *SQLRepository) ListMatrixRows(surveyID int64) ([]MatrixRow, error) {
+	defer observeQuery("ListMatrixRows", time.Now())
+	return ListMatrixRows(r.DB, surveyID)
+}
+
+func (r *SQLRepository) ListSurveyAnswers(surveyID int64) ([]AnswerRow, error) {
+	defer observeQuery("ListSurveyAnswers", time.Now())
+	return ListSurveyAnswers(r.DB, surveyID)
+}
+
+func (r *SQLRepository) Close() error {
+	return r.DB.Close()
+}
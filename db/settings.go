@@ -0,0 +1,82 @@
+package db
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// SettingRow is a row of the SETTINGS table: runtime switches of EUSurvey
+// such as DisableWebserviceAPI. Format describes the allowed values, e.g.
+// "true / false".
+type SettingRow struct {
+	Key    string
+	Value  sql.NullString
+	Format sql.NullString
+}
+
+// ErrSettingNotFound is returned by GetSetting for a key without a row.
+var ErrSettingNotFound = errors.New("setting not found")
+
+// querier is satisfied by *sql.DB and *sql.Tx.
+type querier interface {
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func ListSettings(db *sql.DB) ([]SettingRow, error) {
+	rows, err := db.Query(`SELECT SETTINGS_KEY, SETTINGS_VALUE, SETTINGS_FORMAT FROM SETTINGS ORDER BY SETTINGS_KEY`)
+	if err != nil {
+		return nil, fmt.Errorf("listing settings: %w", err)
+	}
+	defer rows.Close()
+
+	var settings []SettingRow
+	for rows.Next() {
+		var s SettingRow
+		if err := rows.Scan(&s.Key, &s.Value, &s.Format); err != nil {
+			return nil, fmt.Errorf("scanning setting: %w", err)
+		}
+		settings = append(settings, s)
+	}
+	return settings, rows.Err()
+}
+
+// GetSetting returns the row of key, or ErrSettingNotFound.
+func GetSetting(q querier, key string) (*SettingRow, error) {
+	s := SettingRow{Key: key}
+	err := q.QueryRow(`SELECT SETTINGS_VALUE, SETTINGS_FORMAT FROM SETTINGS WHERE SETTINGS_KEY = ?`, key).
+		Scan(&s.Value, &s.Format)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%s: %w", key, ErrSettingNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading setting %s: %w", key, err)
+	}
+	return &s, nil
+}
+
+// SetSetting updates the value of an existing key, or inserts it with the
+// given format when create is set. It fails unless exactly one row changes.
+func SetSetting(q querier, key, value, format string, create bool) error {
+	var res sql.Result
+	var err error
+	if create {
+		res, err = q.Exec(`INSERT INTO SETTINGS (SETTINGS_KEY, SETTINGS_VALUE, SETTINGS_FORMAT) VALUES (?, ?, ?)`,
+			key, value, format)
+	} else {
+		res, err = q.Exec(`UPDATE SETTINGS SET SETTINGS_VALUE = ? WHERE SETTINGS_KEY = ?`, value, key)
+	}
+	if err != nil {
+		return fmt.Errorf("writing setting %s: %w", key, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("writing setting %s: %w", key, err)
+	}
+	if n != 1 {
+		return fmt.Errorf("writing setting %s: %d rows affected, expected 1", key, n)
+	}
+	return nil
+}
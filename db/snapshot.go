@@ -0,0 +1,220 @@
+package db
+
+import (
+	"database/sql"
+	"eusurveymgr/log"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// snapshotSchema mirrors the subset of the EUSurvey schema used by the db
+// package, so the same queries run unchanged against a snapshot.
+var snapshotSchema = []string{
+	`CREATE TABLE SNAPSHOT_INFO (INFO_KEY TEXT PRIMARY KEY, INFO_VALUE TEXT)`,
+	`CREATE TABLE SURVEYS (SURVEY_ID INTEGER PRIMARY KEY, TITLE TEXT, SURVEYNAME TEXT,
+		SURVEY_UID TEXT, SURVEY_CREATED TEXT, ISPUBLISHED INTEGER)`,
+	`CREATE TABLE ANSWERS_SET (ANSWER_SET_ID INTEGER PRIMARY KEY, SURVEY_ID INTEGER,
+		UNIQUECODE TEXT, ANSWER_SET_DATE TEXT, ANSWER_SET_UPDATE TEXT, ISDRAFT INTEGER)`,
+	`CREATE TABLE ANSWERS (ANSWER_ID INTEGER PRIMARY KEY, AS_ID INTEGER, QUESTION_UID TEXT,
+		PA_ID INTEGER, PA_UID TEXT, VALUE TEXT)`,
+	`CREATE TABLE ELEMENTS (ID INTEGER PRIMARY KEY, ELEM_UID TEXT, ETITLE TEXT, ETYPE TEXT,
+		QOPTIONAL INTEGER)`,
+	`CREATE TABLE SURVEYS_ELEMENTS (SURVEYS_SURVEY_ID INTEGER, elements_ID INTEGER,
+		elements_ORDER INTEGER)`,
+	`CREATE TABLE ELEMENTS_ELEMENTS (ELEMENTS_ID INTEGER, possibleAnswers_ID INTEGER)`,
+	`CREATE INDEX idx_answers_set_survey ON ANSWERS_SET (SURVEY_ID)`,
+	`CREATE INDEX idx_answers_as ON ANSWERS (AS_ID, PA_ID)`,
+	`CREATE INDEX idx_elements_uid ON ELEMENTS (ELEM_UID)`,
+}
+
+type snapshotTable struct {
+	name    string
+	columns []string
+	query   string
+	args    int
+}
+
+// snapshotTables lists, per table, the MySQL query selecting the rows that
+// belong to one survey. Each query takes the survey ID args times. BIT
+// columns are converted to integers with +0.
+var snapshotTables = []snapshotTable{
+	{
+		name:    "SURVEYS",
+		columns: []string{"SURVEY_ID", "TITLE", "SURVEYNAME", "SURVEY_UID", "SURVEY_CREATED", "ISPUBLISHED"},
+		query: `SELECT SURVEY_ID, TITLE, SURVEYNAME, SURVEY_UID, SURVEY_CREATED, ISPUBLISHED+0
+			FROM SURVEYS WHERE SURVEY_ID = ?`,
+		args: 1,
+	},
+	{
+		name:    "ANSWERS_SET",
+		columns: []string{"ANSWER_SET_ID", "SURVEY_ID", "UNIQUECODE", "ANSWER_SET_DATE", "ANSWER_SET_UPDATE", "ISDRAFT"},
+		query: `SELECT ANSWER_SET_ID, SURVEY_ID, UNIQUECODE, ANSWER_SET_DATE, ANSWER_SET_UPDATE, ISDRAFT+0
+			FROM ANSWERS_SET WHERE SURVEY_ID = ?`,
+		args: 1,
+	},
+	{
+		name:    "ANSWERS",
+		columns: []string{"ANSWER_ID", "AS_ID", "QUESTION_UID", "PA_ID", "PA_UID", "VALUE"},
+		query: `SELECT a.ANSWER_ID, a.AS_ID, a.QUESTION_UID, a.PA_ID, a.PA_UID, a.VALUE
+			FROM ANSWERS a
+			JOIN ANSWERS_SET a_set ON a_set.ANSWER_SET_ID = a.AS_ID
+			WHERE a_set.SURVEY_ID = ?`,
+		args: 1,
+	},
+	{
+		name:    "SURVEYS_ELEMENTS",
+		columns: []string{"SURVEYS_SURVEY_ID", "elements_ID", "elements_ORDER"},
+		query: `SELECT SURVEYS_SURVEY_ID, elements_ID, elements_ORDER
+			FROM SURVEYS_ELEMENTS WHERE SURVEYS_SURVEY_ID = ?`,
+		args: 1,
+	},
+	{
+		name:    "ELEMENTS_ELEMENTS",
+		columns: []string{"ELEMENTS_ID", "possibleAnswers_ID"},
+		query: `SELECT ee.ELEMENTS_ID, ee.possibleAnswers_ID
+			FROM ELEMENTS_ELEMENTS ee
+			JOIN SURVEYS_ELEMENTS se ON se.elements_ID = ee.ELEMENTS_ID
+			WHERE se.SURVEYS_SURVEY_ID = ?`,
+		args: 1,
+	},
+	{
+		// Survey elements, their options, and every element referenced by
+		// an answer's PA_UID (GetResponses joins on it).
+		name:    "ELEMENTS",
+		columns: []string{"ID", "ELEM_UID", "ETITLE", "ETYPE", "QOPTIONAL"},
+		query: `SELECT e.ID, e.ELEM_UID, e.ETITLE, e.ETYPE, e.QOPTIONAL+0
+			FROM ELEMENTS e
+			WHERE e.ID IN (
+			    SELECT se.elements_ID FROM SURVEYS_ELEMENTS se WHERE se.SURVEYS_SURVEY_ID = ?
+			    UNION
+			    SELECT ee.possibleAnswers_ID FROM ELEMENTS_ELEMENTS ee
+			    JOIN SURVEYS_ELEMENTS se ON se.elements_ID = ee.ELEMENTS_ID
+			    WHERE se.SURVEYS_SURVEY_ID = ?
+			)
+			OR e.ELEM_UID IN (
+			    SELECT a.PA_UID FROM ANSWERS a
+			    JOIN ANSWERS_SET a_set ON a_set.ANSWER_SET_ID = a.AS_ID
+			    WHERE a_set.SURVEY_ID = ?
+			)`,
+		args: 3,
+	},
+}
+
+// CreateSnapshot copies the rows of one survey from src (MySQL) into a new
+// SQLite file at path. The file must not exist yet; it is removed again if
+// the copy fails.
+func CreateSnapshot(src *sql.DB, surveyID int64, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return fmt.Errorf("snapshot %s already exists", path)
+	}
+	if err := createSnapshot(src, surveyID, path); err != nil {
+		os.Remove(path)
+		return err
+	}
+	return nil
+}
+
+func createSnapshot(src *sql.DB, surveyID int64, path string) error {
+	dst, err := sql.Open("sqlite", path)
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+	defer dst.Close()
+
+	tx, err := dst.Begin()
+	if err != nil {
+		return fmt.Errorf("creating snapshot: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, stmt := range snapshotSchema {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("creating snapshot schema: %w", err)
+		}
+	}
+
+	for _, t := range snapshotTables {
+		args := make([]any, t.args)
+		for i := range args {
+			args[i] = surveyID
+		}
+		n, err := copyRows(src, tx, t, args)
+		if err != nil {
+			return fmt.Errorf("copying %s: %w", t.name, err)
+		}
+		log.Infof("SNAPSHOT -- %s: %d rows", t.name, n)
+	}
+
+	info := map[string]string{
+		"survey_id": fmt.Sprint(surveyID),
+		"created":   time.Now().Format(time.RFC3339),
+	}
+	for k, v := range info {
+		if _, err := tx.Exec(`INSERT INTO SNAPSHOT_INFO (INFO_KEY, INFO_VALUE) VALUES (?, ?)`, k, v); err != nil {
+			return fmt.Errorf("writing snapshot info: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func copyRows(src *sql.DB, dst *sql.Tx, t snapshotTable, args []any) (int, error) {
+	rows, err := src.Query(t.query, args...)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(t.columns)), ",")
+	insert, err := dst.Prepare(fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		t.name, strings.Join(t.columns, ", "), placeholders))
+	if err != nil {
+		return 0, err
+	}
+	defer insert.Close()
+
+	values := make([]any, len(t.columns))
+	ptrs := make([]any, len(t.columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return n, err
+		}
+		// The MySQL driver returns text as []byte; store it as TEXT so
+		// string comparisons (e.g. LookupUniqueCode) behave as in MySQL.
+		for i, v := range values {
+			if b, ok := v.([]byte); ok {
+				values[i] = string(b)
+			}
+		}
+		if _, err := insert.Exec(values...); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// OpenSnapshot opens a SQLite snapshot created by CreateSnapshot read-only.
+func OpenSnapshot(path string) (*sql.DB, error) {
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("opening snapshot: %w", err)
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("opening snapshot: %w", err)
+	}
+	log.Infof("SQLITE -- Opened snapshot %s", path)
+	return db, nil
+}
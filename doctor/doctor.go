@@ -0,0 +1,284 @@
+// Package doctor diagnoses the EUSurvey integration layer by layer: server
+// reachability and TLS, the WebService API, the session login, the database
+// and the SETTINGS flag, and an answer-PDF round trip.
+package doctor
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql"
+	"errors"
+	"eusurveymgr/client"
+	"eusurveymgr/config"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+type Status string
+
+const (
+	Pass Status = "pass"
+	Warn Status = "warn"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+// Result is the outcome of one check. Hint tells how to fix a failure or
+// warning.
+type Result struct {
+	Check      string `json:"check"`
+	Status     Status `json:"status"`
+	Detail     string `json:"detail"`
+	Hint       string `json:"hint,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// Doctor runs the checks in order. Checks that depend on a failed layer are
+// skipped.
+type Doctor struct {
+	Config *config.Configuration
+	Client *client.Client
+	// ConnectDB opens the MySQL database; nil skips the database checks.
+	ConnectDB func() (*sql.DB, error)
+	// Canary is the UNIQUECODE of the test answer used for the PDF round
+	// trip; empty skips it, so that no real respondent's answer is used.
+	Canary  string
+	SkipPDF bool
+
+	db        *sql.DB
+	reachable bool
+	loggedIn  bool
+}
+
+// Run runs every check, calling report after each one.
+func (d *Doctor) Run(report func(Result)) []Result {
+	defer func() {
+		if d.db != nil {
+			d.db.Close()
+		}
+	}()
+	checks := []struct {
+		name string
+		run  func() Result
+	}{
+		{"Reachability and TLS", d.checkReachability},
+		{"WebService API (Basic auth)", d.checkWebservice},
+		{"Session login", d.checkLogin},
+		{"Database connection and grants", d.checkDB},
+		{"DisableWebserviceAPI setting", d.checkSettings},
+		{"Answer PDF round trip", d.checkPDF},
+	}
+	var results []Result
+	for _, c := range checks {
+		start := time.Now()
+		r := c.run()
+		r.Check = c.name
+		r.DurationMS = time.Since(start).Milliseconds()
+		results = append(results, r)
+		if report != nil {
+			report(r)
+		}
+	}
+	return results
+}
+
+func (d *Doctor) checkReachability() Result {
+	if d.Config.BaseURL == "" {
+		return Result{Status: Fail, Detail: "base_url is not set",
+			Hint: "Set base_url to the EUSurvey root, e.g. https://eusurvey.example.org/eusurvey (run 'config init')."}
+	}
+	resp, err := d.Client.HTTPClient.Get(d.Config.BaseURL + "/auth/login")
+	if err != nil {
+		return Result{Status: Fail, Detail: err.Error(), Hint: networkHint(err)}
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	detail := fmt.Sprintf("%s answered HTTP %d", d.Config.BaseURL, resp.StatusCode)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return Result{Status: Fail, Detail: detail + " for /auth/login",
+			Hint: "base_url must point at the EUSurvey web application, including its context path (usually /eusurvey)."}
+	case resp.StatusCode >= 500:
+		return Result{Status: Fail, Detail: detail,
+			Hint: "The server is up but EUSurvey is failing: check that Tomcat deployed the webapp (logs/catalina.out)."}
+	}
+	d.reachable = true
+	if resp.TLS == nil {
+		return Result{Status: Warn, Detail: detail + " over plain HTTP",
+			Hint: "Credentials are sent in clear text; use the https:// URL of the instance."}
+	}
+	detail += ", " + tls.VersionName(resp.TLS.Version)
+	if d.Config.InsecureTLS {
+		return Result{Status: Warn, Detail: detail + ", certificate NOT verified (insecure_tls)",
+			Hint: "Install the CA of the server certificate on this host and set insecure_tls to false."}
+	}
+	if len(resp.TLS.PeerCertificates) > 0 {
+		expires := resp.TLS.PeerCertificates[0].NotAfter
+		detail += ", certificate valid until " + expires.Format(time.DateOnly)
+		if time.Until(expires) < 14*24*time.Hour {
+			return Result{Status: Warn, Detail: detail, Hint: "The server certificate expires within 14 days; renew it."}
+		}
+	}
+	return Result{Status: Pass, Detail: detail}
+}
+
+func networkHint(err error) string {
+	var dnsErr *net.DNSError
+	var certErr *tls.CertificateVerificationError
+	var unknownAuthority x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	switch {
+	case errors.As(err, &dnsErr):
+		return "The host name in base_url does not resolve; check base_url and DNS."
+	case errors.As(err, &unknownAuthority):
+		return "The server certificate is signed by an unknown CA: install the CA certificate on this host (insecure_tls only for testing)."
+	case errors.As(err, &hostnameErr):
+		return "The server certificate does not match the host name in base_url; use the name the certificate was issued for."
+	case errors.As(err, &certErr):
+		return "The server certificate is not trusted (expired or invalid); renew it or install its CA."
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return "Connection refused: is Tomcat running and listening on the port in base_url?"
+	case errors.Is(err, syscall.ETIMEDOUT) || strings.Contains(err.Error(), "Timeout"):
+		return "The connection timed out: check firewalls between this host and the server, or raise timeout_seconds."
+	}
+	return "Check base_url and that the server is reachable from this host."
+}
+
+func (d *Doctor) checkWebservice() Result {
+	if !d.reachable {
+		return Result{Status: Skip, Detail: "server not reachable"}
+	}
+	list, err := d.Client.GetSurveys()
+	if err != nil {
+		r := Result{Status: Fail, Detail: err.Error()}
+		var statusErr *client.StatusError
+		if errors.As(err, &statusErr) {
+			switch statusErr.Code {
+			case http.StatusUnauthorized:
+				r.Hint = "web_user/web_password were rejected; check them (and their source in 'config show --origin')."
+			case http.StatusNotFound:
+				r.Hint = "getMySurveys was not found; check base_url (the old getSurveys endpoint does not exist)."
+			case http.StatusTooManyRequests:
+				r.Hint = "Daily request limit reached: raise webservice.maxrequestsperday in spring.properties."
+			default:
+				r.Hint = "The WebService API may be disabled: set DisableWebserviceAPI to 'false' in SETTINGS and restart Tomcat (see the DisableWebserviceAPI check)."
+			}
+		}
+		return r
+	}
+	return Result{Status: Pass, Detail: fmt.Sprintf("getMySurveys returned %d surveys for %s", len(list.Surveys), d.Config.WebUser)}
+}
+
+func (d *Doctor) checkLogin() Result {
+	if !d.reachable {
+		return Result{Status: Skip, Detail: "server not reachable"}
+	}
+	if err := d.Client.Login(); err != nil {
+		r := Result{Status: Fail, Detail: err.Error(),
+			Hint: "web_user/web_password were rejected by the login form; the same account must be able to sign in to the web UI."}
+		if strings.Contains(err.Error(), "CSRF") {
+			r.Hint = `The login page no longer contains <meta name="_csrf" content="...">: the EUSurvey templates changed; adapt csrfRe in client/session.go.`
+		}
+		return r
+	}
+	d.loggedIn = true
+	return Result{Status: Pass, Detail: "form login with CSRF token succeeded"}
+}
+
+// Tables read by the db commands and by this check.
+var grantTables = []string{"SURVEYS", "ANSWERS_SET", "ANSWERS", "ELEMENTS", "SURVEYS_ELEMENTS", "ELEMENTS_ELEMENTS", "SETTINGS"}
+
+func (d *Doctor) checkDB() Result {
+	if d.ConnectDB == nil {
+		return Result{Status: Skip, Detail: "no MySQL connection configured (db_host empty or --source given)"}
+	}
+	conn, err := d.ConnectDB()
+	if err != nil {
+		return Result{Status: Fail, Detail: err.Error(), Hint: d.mysqlHint(err)}
+	}
+	d.db = conn
+	for _, table := range grantTables {
+		if _, err := conn.Exec("SELECT 1 FROM " + table + " LIMIT 1"); err != nil {
+			return Result{Status: Fail, Detail: fmt.Sprintf("reading %s: %v", table, err), Hint: d.mysqlHint(err)}
+		}
+	}
+	return Result{Status: Pass, Detail: fmt.Sprintf("connected to %s@%s:%d/%s, SELECT on %s",
+		d.Config.DBUser, d.Config.DBHost, d.Config.DBPort, d.Config.DBName, strings.Join(grantTables, ", "))}
+}
+
+func (d *Doctor) mysqlHint(err error) string {
+	grant := fmt.Sprintf("GRANT SELECT ON %s.* TO '%s'@'<this host>';", d.Config.DBName, d.Config.DBUser)
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		switch myErr.Number {
+		case 1045:
+			return "Access denied: check db_user/db_password and that the account may connect from this host."
+		case 1049:
+			return "Unknown database: check db_name (usually eusurveydb)."
+		case 1044, 1142:
+			return "The account lacks read access: " + grant
+		case 1146:
+			return "Table missing: db_name does not point at the EUSurvey schema."
+		}
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return "Connection refused: check db_host/db_port, that MySQL listens on that address (bind-address), or open the SSH tunnel."
+	}
+	return "Check db_host, db_port and network access to MySQL."
+}
+
+func (d *Doctor) checkSettings() Result {
+	if d.db == nil {
+		return Result{Status: Skip, Detail: "database not available"}
+	}
+	var value string
+	err := d.db.QueryRow("SELECT SETTINGS_VALUE FROM SETTINGS WHERE SETTINGS_KEY = 'DisableWebserviceAPI'").Scan(&value)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return Result{Status: Warn, Detail: "DisableWebserviceAPI is not set",
+			Hint: "Run 'eusurveymgr db settings set DisableWebserviceAPI false --create', then restart Tomcat."}
+	case err != nil:
+		return Result{Status: Fail, Detail: err.Error(), Hint: d.mysqlHint(err)}
+	case strings.EqualFold(strings.TrimSpace(value), "false"):
+		return Result{Status: Pass, Detail: "DisableWebserviceAPI = false"}
+	}
+	return Result{Status: Fail, Detail: fmt.Sprintf("DisableWebserviceAPI = %s", value),
+		Hint: "Run 'eusurveymgr db settings set DisableWebserviceAPI false', then restart Tomcat (the value is read at startup)."}
+}
+
+func (d *Doctor) checkPDF() Result {
+	switch {
+	case d.SkipPDF:
+		return Result{Status: Skip, Detail: "--skip-pdf"}
+	case !d.loggedIn:
+		return Result{Status: Skip, Detail: "session login failed"}
+	}
+	code := d.Canary
+	if code == "" {
+		return Result{Status: Skip, Detail: "no --canary answer given",
+			Hint: "Submit a test answer and pass its UNIQUECODE with --canary."}
+	}
+	data, err := d.Client.GetAnswerPDF(code, d.Config.TimeoutSeconds)
+	if err != nil {
+		r := Result{Status: Fail, Detail: fmt.Sprintf("%s: %v", code, err),
+			Hint: "PDF generation fails on the server: if catalina.out shows NoClassDefFoundError javax/xml/bind/JAXBException, add jaxb-api-2.2.11.jar (2.2.11, not 2.3.x) to WEB-INF/lib and restart Tomcat."}
+		if strings.Contains(err.Error(), "timed out") {
+			r.Hint = "The PDF was not ready in time: if catalina.out shows NoClassDefFoundError javax/xml/bind/JAXBException, add jaxb-api-2.2.11.jar to WEB-INF/lib; otherwise raise timeout_seconds or export.poolSize."
+		}
+		return r
+	}
+	if !bytes.HasPrefix(data, []byte("%PDF-")) {
+		return Result{Status: Fail, Detail: fmt.Sprintf("%s: download is not a PDF (%d bytes)", code, len(data)),
+			Hint: "The session probably expired or the download was redirected to the login page; rerun with -v."}
+	}
+	return Result{Status: Pass, Detail: fmt.Sprintf("%s: generated and downloaded %d bytes", code, len(data))}
+}
@@ -0,0 +1,116 @@
+package gdpr
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Bundle is everything held about one data subject.
+type Bundle struct {
+	Email      string      `json:"email"`
+	Generated  time.Time   `json:"generated"`
+	AnswerSets []AnswerSet `json:"answer_sets"`
+}
+
+// AnswerSet is one survey submission of the data subject.
+type AnswerSet struct {
+	SurveyID    int64      `json:"survey_id"`
+	SurveyAlias string     `json:"survey_alias"`
+	SurveyTitle string     `json:"survey_title"`
+	AnswerSetID int64      `json:"answer_set_id"`
+	UniqueCode  string     `json:"uniquecode"`
+	Date        string     `json:"date"`
+	Responses   []Response `json:"responses"`
+	PDF         string     `json:"pdf,omitempty"`
+	PDFError    string     `json:"pdf_error,omitempty"`
+
+	pdfData []byte
+}
+
+// Response is a single stored answer value.
+type Response struct {
+	PA_ID    int    `json:"pa_id"`
+	Question string `json:"question"`
+	Value    string `json:"value"`
+}
+
+// SetPDF attaches the answer PDF; it is stored under pdfs/ in the zip.
+func (a *AnswerSet) SetPDF(data []byte) {
+	a.pdfData = data
+	a.PDF = fmt.Sprintf("pdfs/%d--%s.pdf", a.AnswerSetID, a.SurveyAlias)
+}
+
+// WriteZip writes the bundle as a zip with a human-readable index.txt, a
+// machine-readable data.json and the answer PDFs.
+func WriteZip(w io.Writer, b *Bundle) error {
+	zw := zip.NewWriter(w)
+
+	f, err := zw.Create("index.txt")
+	if err != nil {
+		return err
+	}
+	if err := writeIndex(f, b); err != nil {
+		return err
+	}
+
+	f, err = zw.Create("data.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+
+	for _, a := range b.AnswerSets {
+		if a.pdfData == nil {
+			continue
+		}
+		f, err := zw.Create(a.PDF)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(a.pdfData); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+func writeIndex(w io.Writer, b *Bundle) error {
+	fmt.Fprintf(w, "Personal data held for %s\n", b.Email)
+	fmt.Fprintf(w, "Generated %s\n", b.Generated.Format(time.RFC1123))
+	fmt.Fprintf(w, "Survey submissions found: %d\n\n", len(b.AnswerSets))
+	fmt.Fprintln(w, "data.json contains the same information in machine-readable form.")
+
+	for i, a := range b.AnswerSets {
+		fmt.Fprintf(w, "\n%s\n", strings.Repeat("=", 72))
+		fmt.Fprintf(w, "%d. %s (%s, survey %d)\n", i+1, a.SurveyTitle, a.SurveyAlias, a.SurveyID)
+		fmt.Fprintf(w, "   Submitted:     %s\n", a.Date)
+		fmt.Fprintf(w, "   Answer set ID: %d\n", a.AnswerSetID)
+		fmt.Fprintf(w, "   Unique code:   %s\n", a.UniqueCode)
+		switch {
+		case a.PDF != "":
+			fmt.Fprintf(w, "   PDF:           %s\n", a.PDF)
+		case a.PDFError != "":
+			fmt.Fprintf(w, "   PDF:           not available (%s)\n", a.PDFError)
+		}
+		fmt.Fprintln(w)
+
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "   QUESTION\tANSWER")
+		for _, r := range a.Responses {
+			fmt.Fprintf(tw, "   %s\t%s\n", r.Question, strings.Join(strings.Fields(r.Value), " "))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
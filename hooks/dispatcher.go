@@ -0,0 +1,264 @@
+package hooks
+
+import (
+	"context"
+	"eusurveymgr/config"
+	"eusurveymgr/db"
+	"eusurveymgr/lockfile"
+	"eusurveymgr/log"
+	"eusurveymgr/metrics"
+	"eusurveymgr/watch"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// Retry backoff: 30s after the first failure, doubling up to one hour.
+const (
+	retryBase = 30 * time.Second
+	retryMax  = time.Hour
+)
+
+// Dispatcher polls the surveys of the configured hooks and delivers each new
+// answer set to each hook once. New answer sets are recorded as pending in
+// the state file before delivery, so deliveries survive restarts (at least
+// once: a crash between delivery and saving repeats that delivery).
+//
+// Tick and Retry hold a lock on the state file (hooks-state.json.lock) and
+// reload the state under it, so 'hooks retry' and several 'hooks run'
+// processes sharing the state dir neither lose updates nor deliver twice.
+type Dispatcher struct {
+	Repo     db.SurveyRepository
+	StateDir string
+	// Backfill makes hooks without state deliver every existing answer set
+	// instead of starting after the newest one.
+	Backfill bool
+	// Transform, if set, is applied to each event before it is queued
+	// (e.g. pseudonymisation of the identity).
+	Transform func(*watch.Event)
+
+	hooks    []config.Hook
+	handlers map[string]Handler
+	state    *State
+	// seen is the highest ANSWER_SET_ID per survey counted as a new
+	// response.
+	seen map[int64]int64
+}
+
+// NewDispatcher validates the hooks and loads their delivery state.
+func NewDispatcher(repo db.SurveyRepository, hooks []config.Hook, stateDir string) (*Dispatcher, error) {
+	d := &Dispatcher{Repo: repo, StateDir: stateDir, hooks: hooks,
+		handlers: make(map[string]Handler), seen: make(map[int64]int64)}
+	for _, h := range hooks {
+		if h.Name == "" {
+			return nil, fmt.Errorf("hook without name")
+		}
+		if _, dup := d.handlers[h.Name]; dup {
+			return nil, fmt.Errorf("duplicate hook name %q", h.Name)
+		}
+		if h.Survey == 0 {
+			return nil, fmt.Errorf("hook %q: survey is required", h.Name)
+		}
+		handler, err := NewHandler(h)
+		if err != nil {
+			return nil, err
+		}
+		d.handlers[h.Name] = handler
+	}
+	state, err := LoadState(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	d.state = state
+	return d, nil
+}
+
+// State returns the current delivery state.
+func (d *Dispatcher) State() *State {
+	return d.state
+}
+
+// lock takes the state lock and reloads the state, which another process
+// may have changed since this one last saved it.
+func (d *Dispatcher) lock() (func(), error) {
+	unlock, err := lockfile.Lock(filepath.Join(d.StateDir, StateFile+".lock"))
+	if err != nil {
+		return nil, err
+	}
+	state, err := LoadState(d.StateDir)
+	if err != nil {
+		unlock()
+		return nil, err
+	}
+	d.state = state
+	return unlock, nil
+}
+
+// Tick runs one cycle: queue new answer sets for every hook, attempt the
+// deliveries that are due, and save the state.
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	unlock, err := d.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	now := time.Now()
+	for _, h := range d.hooks {
+		if err := d.queue(h); err != nil {
+			log.Warnf("HOOK %s -- polling survey %d: %v", h.Name, h.Survey, err)
+		}
+	}
+	if err := d.state.Save(d.StateDir); err != nil {
+		return fmt.Errorf("saving hook state: %w", err)
+	}
+	for _, h := range d.hooks {
+		d.deliver(ctx, h, now)
+	}
+	if err := d.state.Save(d.StateDir); err != nil {
+		return fmt.Errorf("saving hook state: %w", err)
+	}
+	return nil
+}
+
+// Run calls Tick every interval until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.Tick(ctx); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) queue(h config.Hook) error {
+	hs := d.state.Hooks[h.Name]
+	if hs == nil || hs.Survey != h.Survey {
+		// New hook (or survey changed): start after the newest answer set
+		// unless backfilling.
+		hs = &HookState{Survey: h.Survey}
+		if !d.Backfill {
+			w := &watch.Watcher{Repo: d.Repo, SurveyID: h.Survey, Uncounted: true}
+			if _, err := w.Poll(); err != nil {
+				return err
+			}
+			hs.LastID = w.LastID
+		}
+		d.state.Hooks[h.Name] = hs
+		log.Infof("HOOK %s -- watching survey %d after ANSWER_SET_ID %d", h.Name, h.Survey, hs.LastID)
+	}
+
+	w := &watch.Watcher{Repo: d.Repo, SurveyID: h.Survey, LastID: hs.LastID, Uncounted: true}
+	answers, err := w.Poll()
+	if err != nil {
+		return err
+	}
+	d.countNew(h.Survey, hs.LastID, answers)
+	for _, a := range answers {
+		e := watch.NewEvent(h.Survey, a)
+		if d.Transform != nil {
+			d.Transform(&e)
+		}
+		hs.Pending = append(hs.Pending, Delivery{Event: e})
+	}
+	hs.LastID = w.LastID
+	if len(answers) > 0 {
+		log.Infof("HOOK %s -- %d new answer sets", h.Name, len(answers))
+	}
+	return nil
+}
+
+// countNew adds the answer sets of survey polled after lastID to the
+// new responses metric, each once however many hooks watch the survey.
+// Answer sets polled from lastID 0 already existed and are not counted.
+func (d *Dispatcher) countNew(survey, lastID int64, answers []db.AnswerSetRow) {
+	seen, ok := d.seen[survey]
+	if !ok {
+		seen = lastID
+	}
+	n := 0
+	for _, a := range answers {
+		if a.AnswerSetID > seen {
+			seen = a.AnswerSetID
+			n++
+		}
+	}
+	if (ok || lastID > 0) && n > 0 {
+		metrics.NewResponses.Add(float64(n), strconv.FormatInt(survey, 10))
+	}
+	d.seen[survey] = seen
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, h config.Hook, now time.Time) {
+	hs := d.state.Hooks[h.Name]
+	if hs == nil {
+		return
+	}
+	handler := d.handlers[h.Name]
+	var pending []Delivery
+	for _, del := range hs.Pending {
+		if ctx.Err() != nil || del.NextAttempt.After(now) {
+			pending = append(pending, del)
+			continue
+		}
+		del.Attempts++
+		err := handler.Deliver(ctx, Payload{Hook: h.Name, Event: del.Event, FiredAt: time.Now(), Attempt: del.Attempts})
+		metrics.HookDeliveries.Inc(h.Name, metrics.Result(err))
+		switch {
+		case err == nil:
+			hs.Delivered++
+			log.Infof("HOOK %s -- delivered ANSWER_SET_ID %d", h.Name, del.Event.AnswerSetID)
+		case del.Attempts >= h.MaxAttempts:
+			del.LastError = err.Error()
+			hs.Failed = append(hs.Failed, del)
+			log.Errorf("HOOK %s -- giving up on ANSWER_SET_ID %d after %d attempts: %v",
+				h.Name, del.Event.AnswerSetID, del.Attempts, err)
+		default:
+			del.LastError = err.Error()
+			del.NextAttempt = now.Add(backoff(del.Attempts))
+			pending = append(pending, del)
+			log.Warnf("HOOK %s -- ANSWER_SET_ID %d attempt %d failed, retrying at %s: %v",
+				h.Name, del.Event.AnswerSetID, del.Attempts, del.NextAttempt.Format(time.TimeOnly), err)
+		}
+	}
+	hs.Pending = pending
+}
+
+// Retry moves the failed deliveries of a hook back to pending with a fresh
+// attempt count, and returns how many were moved.
+func (d *Dispatcher) Retry(name string) (int, error) {
+	if _, ok := d.handlers[name]; !ok {
+		return 0, fmt.Errorf("unknown hook %q", name)
+	}
+	unlock, err := d.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+	hs := d.state.Hooks[name]
+	if hs == nil {
+		return 0, nil
+	}
+	n := len(hs.Failed)
+	for _, del := range hs.Failed {
+		del.Attempts = 0
+		del.NextAttempt = time.Time{}
+		hs.Pending = append(hs.Pending, del)
+	}
+	hs.Failed = nil
+	return n, d.state.Save(d.StateDir)
+}
+
+func backoff(attempts int) time.Duration {
+	delay := retryBase
+	for i := 1; i < attempts && delay < retryMax; i++ {
+		delay *= 2
+	}
+	return min(delay, retryMax)
+}
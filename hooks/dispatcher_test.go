@@ -0,0 +1,83 @@
+package hooks
+
+import (
+	"context"
+	"eusurveymgr/config"
+	"eusurveymgr/db"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var fixture = &db.Fixture{Surveys: []db.FixtureSurvey{{
+	ID: 4609, UID: "survey-uid", Alias: "Check4Test",
+	AnswerSets: []db.FixtureAnswerSet{
+		{ID: 1, UniqueCode: "code-ana", Date: "2026-03-01 10:00:00", Answers: []db.FixtureAnswer{
+			{QuestionUID: "q-name", Value: "Ana Popescu"},
+			{QuestionUID: "q-email", Value: "ana.popescu@example.com"},
+		}},
+	},
+}}}
+
+// newDispatcher returns a backfilling dispatcher of one command hook, as a
+// separate 'hooks run' or 'hooks retry' process would create it.
+func newDispatcher(t *testing.T, stateDir, script string) *Dispatcher {
+	t.Helper()
+	hook := config.Hook{Name: "crm", Survey: 4609, Command: []string{"sh", "-c", script},
+		MaxAttempts: 1, TimeoutSeconds: 10}
+	d, err := NewDispatcher(db.NewMemoryRepository(fixture), []config.Hook{hook}, stateDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Backfill = true
+	return d
+}
+
+func TestTwoRunnersDeliverOnce(t *testing.T) {
+	dir := t.TempDir()
+	out := filepath.Join(dir, "delivered")
+	script := "cat >> " + out + "; echo >> " + out
+	first := newDispatcher(t, dir, script)
+	second := newDispatcher(t, dir, script)
+	for _, d := range []*Dispatcher{first, second} {
+		if err := d.Tick(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(content), "code-ana"); n != 1 {
+		t.Errorf("answer set delivered %d times, want once", n)
+	}
+}
+
+func TestRetryWhileRunning(t *testing.T) {
+	dir := t.TempDir()
+	// Fails the first time only.
+	script := "test -e " + filepath.Join(dir, "failed") + " || { touch " + filepath.Join(dir, "failed") + "; exit 1; }"
+	runner := newDispatcher(t, dir, script)
+	if err := runner.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(runner.State().Hooks["crm"].Failed); n != 1 {
+		t.Fatalf("got %d failed deliveries, want 1", n)
+	}
+
+	n, err := newDispatcher(t, dir, script).Retry("crm")
+	if err != nil || n != 1 {
+		t.Fatalf("retry: got %d, %v", n, err)
+	}
+	if err := runner.Tick(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	state, err := LoadState(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hs := state.Hooks["crm"]; hs.Delivered != 1 || len(hs.Failed) != 0 {
+		t.Errorf("retried delivery lost: delivered %d, failed %d", hs.Delivered, len(hs.Failed))
+	}
+}
@@ -0,0 +1,125 @@
+// Package hooks delivers new answer sets to configured handlers: HTTP
+// webhooks and local commands. Deliveries are tracked in a state file so each
+// answer set is handed to each hook once, with retries on failure.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"eusurveymgr/config"
+	"eusurveymgr/watch"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Payload is the JSON body POSTed to webhooks and passed to commands on stdin.
+type Payload struct {
+	Hook string `json:"hook"`
+	watch.Event
+	FiredAt time.Time `json:"fired_at"`
+	Attempt int       `json:"attempt"`
+}
+
+// Handler delivers one payload. A nil error means the delivery succeeded.
+type Handler interface {
+	Deliver(ctx context.Context, p Payload) error
+}
+
+// NewHandler builds the handler described by a hook configuration.
+func NewHandler(h config.Hook) (Handler, error) {
+	switch {
+	case h.URL != "" && len(h.Command) > 0:
+		return nil, fmt.Errorf("hook %q: set either url or command, not both", h.Name)
+	case h.URL != "":
+		return &HTTPHandler{
+			URL:     h.URL,
+			Headers: h.Headers,
+			Client:  &http.Client{Timeout: time.Duration(h.TimeoutSeconds) * time.Second},
+		}, nil
+	case len(h.Command) > 0:
+		return &CommandHandler{
+			Argv:    h.Command,
+			Timeout: time.Duration(h.TimeoutSeconds) * time.Second,
+		}, nil
+	}
+	return nil, fmt.Errorf("hook %q: url or command is required", h.Name)
+}
+
+// HTTPHandler POSTs the payload as JSON. Any 2xx status is a success.
+type HTTPHandler struct {
+	URL     string
+	Headers map[string]string
+	Client  *http.Client
+}
+
+func (h *HTTPHandler) Deliver(ctx context.Context, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "eusurveymgr-hooks")
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := h.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return fmt.Errorf("POST %s: HTTP %d: %s", h.URL, resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	return nil
+}
+
+// CommandHandler runs a local command with the payload in EUSURVEY_*
+// environment variables and as JSON on stdin. Exit status 0 is a success.
+type CommandHandler struct {
+	Argv    []string
+	Timeout time.Duration
+}
+
+func (h *CommandHandler) Deliver(ctx context.Context, p Payload) error {
+	body, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+	if h.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, h.Argv[0], h.Argv[1:]...)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"EUSURVEY_HOOK="+p.Hook,
+		"EUSURVEY_SURVEY_ID="+strconv.FormatInt(p.SurveyID, 10),
+		"EUSURVEY_ANSWER_SET_ID="+strconv.FormatInt(p.AnswerSetID, 10),
+		"EUSURVEY_UNIQUECODE="+p.UniqueCode,
+		"EUSURVEY_DATE="+p.Date,
+		"EUSURVEY_NAME="+p.Name,
+		"EUSURVEY_EMAIL="+p.Email,
+		"EUSURVEY_FIRED_AT="+p.FiredAt.Format(time.RFC3339),
+		"EUSURVEY_ATTEMPT="+strconv.Itoa(p.Attempt),
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(out) > 200 {
+			out = out[:200]
+		}
+		return fmt.Errorf("%s: %w: %s", h.Argv[0], err, bytes.TrimSpace(out))
+	}
+	return nil
+}
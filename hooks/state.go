@@ -0,0 +1,90 @@
+package hooks
+
+import (
+	"encoding/json"
+	"errors"
+	"eusurveymgr/watch"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StateFile is the name of the delivery state file inside the state dir.
+const StateFile = "hooks-state.json"
+
+// State is the delivery tracking of all hooks, keyed by hook name.
+type State struct {
+	Hooks map[string]*HookState `json:"hooks"`
+}
+
+// HookState is the position and outstanding deliveries of one hook.
+type HookState struct {
+	Survey int64 `json:"survey"`
+	// LastID is the highest ANSWER_SET_ID queued for this hook.
+	LastID    int64      `json:"last_id"`
+	Delivered int        `json:"delivered"`
+	Pending   []Delivery `json:"pending,omitempty"`
+	// Failed holds deliveries that used up their attempts; 'hooks retry'
+	// moves them back to Pending.
+	Failed []Delivery `json:"failed,omitempty"`
+}
+
+// Delivery is one answer set waiting to be delivered to a hook.
+type Delivery struct {
+	Event       watch.Event `json:"event"`
+	Attempts    int         `json:"attempts"`
+	NextAttempt time.Time   `json:"next_attempt"`
+	LastError   string      `json:"last_error,omitempty"`
+}
+
+// LoadState reads the state file in dir. A missing file is an empty state.
+func LoadState(dir string) (*State, error) {
+	s := &State{Hooks: make(map[string]*HookState)}
+	content, err := os.ReadFile(filepath.Join(dir, StateFile))
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(content, s); err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", StateFile, err)
+	}
+	if s.Hooks == nil {
+		s.Hooks = make(map[string]*HookState)
+	}
+	return s, nil
+}
+
+// Save writes the state file atomically (temporary file + rename), so an
+// interrupted run never leaves a truncated file behind. Callers hold the
+// state lock (see Dispatcher).
+func (s *State) Save(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, StateFile+".*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(content); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(dir, StateFile)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
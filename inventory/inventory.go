@@ -0,0 +1,176 @@
+// Package inventory joins the surveys seen by the WebService API with the
+// surveys in the database and flags where the two disagree.
+package inventory
+
+import (
+	"errors"
+	"eusurveymgr/client"
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Differences reported in Entry.Flags.
+const (
+	NotInDB         = "not in DB"
+	NotInAPI        = "not in API"
+	AliasDiffers    = "alias differs"
+	IDDiffers       = "ID differs"
+	StatusDiffers   = "status differs"
+	ResultsDiffer   = "results differ"
+	MetadataMissing = "no metadata"
+)
+
+// Entry is one survey, matched by SURVEY_UID or, failing that, by alias.
+type Entry struct {
+	UID   string
+	Alias string
+	Title string
+	// API is the survey as listed by getMySurveys; nil if the API user does
+	// not see it.
+	API *client.Survey
+	// Meta is the getSurveyMetadata result; nil if not fetched, see
+	// MetaError.
+	Meta      *client.SurveyMetadata
+	MetaError string
+	// DB is the latest version of the survey in the database; nil if there
+	// is none.
+	DB    *db.SurveyRow
+	Flags []string
+}
+
+// Inventory builds the joined survey list.
+type Inventory struct {
+	Client *client.Client
+	// Concurrency is the number of metadata requests in flight (default 4).
+	Concurrency int
+	Logger      *log.Logger
+}
+
+// Build lists the API surveys, fetches their metadata concurrently and
+// joins them with dbSurveys. With a client budget, only as many metadata
+// requests are made as the day's budget has left; the other surveys get a
+// MetaError. API surveys come first in API order, then the surveys that are
+// only in the database by ID.
+func (inv *Inventory) Build(dbSurveys []db.SurveyRow) ([]Entry, error) {
+	logger := inv.Logger
+	if logger == nil {
+		logger = log.Default()
+	}
+	list, err := inv.Client.GetSurveys()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(list.Surveys))
+	for i := range list.Surveys {
+		s := &list.Surveys[i]
+		entries[i] = Entry{UID: s.UID, Alias: s.Alias, Title: s.Title, API: s}
+	}
+	var dbOnly []Entry
+	for i := range dbSurveys {
+		row := &dbSurveys[i]
+		if e := match(entries, row); e != nil {
+			e.DB = row
+			continue
+		}
+		dbOnly = append(dbOnly, Entry{UID: row.SurveyUID, Alias: row.Alias, Title: row.Title, DB: row})
+	}
+	sort.Slice(dbOnly, func(i, j int) bool { return dbOnly[i].DB.SurveyID < dbOnly[j].DB.SurveyID })
+
+	fetch := len(entries)
+	if inv.Client.Budget != nil {
+		remaining, err := inv.Client.Budget.Remaining()
+		if err != nil {
+			return nil, err
+		}
+		if remaining < fetch {
+			logger.Warnf("INVENTORY -- %d requests left in today's API budget, fetching metadata of %d of %d surveys",
+				remaining, remaining, fetch)
+			fetch = remaining
+		}
+	}
+	for i := fetch; i < len(entries); i++ {
+		entries[i].MetaError = client.ErrBudgetExhausted.Error()
+	}
+	inv.fetchMetadata(entries[:fetch], logger)
+
+	entries = append(entries, dbOnly...)
+	for i := range entries {
+		entries[i].Flags = compare(&entries[i])
+	}
+	return entries, nil
+}
+
+// match finds the entry of a database survey: by UID, else by alias.
+func match(entries []Entry, row *db.SurveyRow) *Entry {
+	for i := range entries {
+		if entries[i].UID != "" && entries[i].UID == row.SurveyUID {
+			return &entries[i]
+		}
+	}
+	for i := range entries {
+		if entries[i].DB == nil && entries[i].Alias == row.Alias {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+func (inv *Inventory) fetchMetadata(entries []Entry, logger *log.Logger) {
+	workers := inv.Concurrency
+	if workers <= 0 {
+		workers = 4
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i := range entries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(e *Entry) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			meta, err := inv.Client.GetSurveyMetadata(e.Alias)
+			if err != nil {
+				if !errors.Is(err, client.ErrBudgetExhausted) {
+					logger.With("alias", e.Alias).Warnf("INVENTORY -- %v", err)
+				}
+				e.MetaError = err.Error()
+				return
+			}
+			e.Meta = meta
+		}(&entries[i])
+	}
+	wg.Wait()
+}
+
+// compare lists how the API and database views of e disagree.
+func compare(e *Entry) []string {
+	flags := []string{}
+	switch {
+	case e.API == nil:
+		return []string{NotInAPI}
+	case e.DB == nil:
+		flags = append(flags, NotInDB)
+	case e.DB.Alias != e.Alias:
+		flags = append(flags, AliasDiffers)
+	}
+	if e.Meta == nil {
+		return append(flags, MetadataMissing)
+	}
+	if e.DB == nil {
+		return flags
+	}
+	if e.Meta.ID != "" && e.Meta.ID != strconv.FormatInt(e.DB.SurveyID, 10) {
+		flags = append(flags, IDDiffers)
+	}
+	if (e.Meta.Status == "published") != e.DB.Published {
+		flags = append(flags, StatusDiffers)
+	}
+	if e.Meta.Results != e.DB.NumAnswers {
+		flags = append(flags, ResultsDiffer)
+	}
+	return flags
+}
@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// HistoryFile is the name of the run history inside the state dir.
+const HistoryFile = "serve-history.jsonl"
+
+// Run statuses.
+const (
+	StatusOK      = "ok"
+	StatusFailed  = "failed"
+	StatusSkipped = "skipped"
+)
+
+// Run is one entry of the run history.
+type Run struct {
+	Job       string    `json:"job"`
+	Scheduled time.Time `json:"scheduled"`
+	Started   time.Time `json:"started,omitzero"`
+	Finished  time.Time `json:"finished,omitzero"`
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Output    string    `json:"output,omitempty"`
+}
+
+// Duration is how long the run took (zero for skipped runs).
+func (r Run) Duration() time.Duration {
+	if r.Started.IsZero() || r.Finished.IsZero() {
+		return 0
+	}
+	return r.Finished.Sub(r.Started)
+}
+
+// History is an append-only JSON Lines log of job runs.
+type History struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewHistory returns the history kept in dir.
+func NewHistory(dir string) *History {
+	return &History{path: filepath.Join(dir, HistoryFile)}
+}
+
+// Append adds a run to the history.
+func (h *History) Append(r Run) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+// Read returns all runs, oldest first. A missing history is empty.
+func (h *History) Read() ([]Run, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, err := os.Open(h.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var runs []Run
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		var r Run
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("%s line %d: %w", HistoryFile, n, err)
+		}
+		runs = append(runs, r)
+	}
+	return runs, scanner.Err()
+}
@@ -0,0 +1,158 @@
+// Package jobs runs recurring jobs on cron schedules and records their
+// history.
+package jobs
+
+import (
+	"context"
+	"eusurveymgr/config"
+	"eusurveymgr/log"
+	"eusurveymgr/metrics"
+	"eusurveymgr/schedule"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ExecFunc runs one job and returns the path its output was written to, if
+// any.
+type ExecFunc func(ctx context.Context, job config.Job, started time.Time) (output string, err error)
+
+type entry struct {
+	job      config.Job
+	schedule *schedule.Schedule
+	next     time.Time
+	// busy is set from the moment a run is triggered until it finishes,
+	// including while it waits for the run lock.
+	busy atomic.Bool
+}
+
+// Scheduler triggers jobs on their schedules. Runs are executed one at a
+// time, and a job that is still running (or waiting to run) when it is due
+// again is skipped, so the same job never runs twice in parallel.
+type Scheduler struct {
+	History *History
+	Exec    ExecFunc
+
+	entries []*entry
+	runMu   sync.Mutex
+	wg      sync.WaitGroup
+}
+
+// NewScheduler parses the schedules of the jobs.
+func NewScheduler(jobs []config.Job, history *History, exec ExecFunc) (*Scheduler, error) {
+	s := &Scheduler{History: history, Exec: exec}
+	seen := make(map[string]bool)
+	for _, j := range jobs {
+		if j.Name == "" {
+			return nil, fmt.Errorf("job without name")
+		}
+		if seen[j.Name] {
+			return nil, fmt.Errorf("duplicate job name %q", j.Name)
+		}
+		seen[j.Name] = true
+		if len(j.Command) == 0 {
+			return nil, fmt.Errorf("job %q: command is required", j.Name)
+		}
+		sched, err := schedule.Parse(j.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", j.Name, err)
+		}
+		s.entries = append(s.entries, &entry{job: j, schedule: sched})
+	}
+	return s, nil
+}
+
+// Next returns the next scheduled time of every job after t, by job name.
+func (s *Scheduler) Next(t time.Time) map[string]time.Time {
+	next := make(map[string]time.Time)
+	for _, e := range s.entries {
+		next[e.job.Name] = e.schedule.Next(t)
+	}
+	return next
+}
+
+// Run triggers jobs until ctx is cancelled, then waits for the running job
+// to finish.
+func (s *Scheduler) Run(ctx context.Context) error {
+	now := time.Now()
+	for _, e := range s.entries {
+		e.next = e.schedule.Next(now)
+		log.Infof("SERVE -- job %s (%s): next run %s", e.job.Name, e.schedule, e.next.Format(time.DateTime))
+	}
+	defer s.wg.Wait()
+
+	for {
+		var earliest time.Time
+		for _, e := range s.entries {
+			if !e.next.IsZero() && (earliest.IsZero() || e.next.Before(earliest)) {
+				earliest = e.next
+			}
+		}
+		if earliest.IsZero() {
+			<-ctx.Done()
+			return nil
+		}
+
+		timer := time.NewTimer(time.Until(earliest))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		for _, e := range s.entries {
+			if e.next.IsZero() || e.next.After(now) {
+				continue
+			}
+			s.trigger(ctx, e, e.next)
+			e.next = e.schedule.Next(now)
+		}
+	}
+}
+
+func (s *Scheduler) trigger(ctx context.Context, e *entry, scheduled time.Time) {
+	if !e.busy.CompareAndSwap(false, true) {
+		log.Warnf("SERVE -- job %s: previous run still in progress, skipping", e.job.Name)
+		s.record(Run{Job: e.job.Name, Scheduled: scheduled, Status: StatusSkipped,
+			Error: "previous run still in progress"})
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer e.busy.Store(false)
+		s.runMu.Lock()
+		defer s.runMu.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+
+		r := Run{Job: e.job.Name, Scheduled: scheduled, Started: time.Now()}
+		log.Infof("SERVE -- job %s: starting", e.job.Name)
+		output, err := s.Exec(ctx, e.job, r.Started)
+		r.Finished = time.Now()
+		r.Output = output
+		r.Status = StatusOK
+		if err != nil {
+			r.Status = StatusFailed
+			r.Error = err.Error()
+			log.Errorf("SERVE -- job %s: failed after %s: %v", e.job.Name, r.Duration().Round(time.Second), err)
+		} else {
+			log.Infof("SERVE -- job %s: done in %s", e.job.Name, r.Duration().Round(time.Second))
+		}
+		s.record(r)
+	}()
+}
+
+func (s *Scheduler) record(r Run) {
+	metrics.JobRuns.Inc(r.Job, r.Status)
+	if r.Status != StatusSkipped {
+		metrics.JobDuration.Observe(r.Duration().Seconds(), r.Job)
+	}
+	if err := s.History.Append(r); err != nil {
+		log.Errorf("SERVE -- writing run history: %v", err)
+	}
+}
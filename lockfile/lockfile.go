@@ -0,0 +1,30 @@
+// Package lockfile serialises eusurveymgr processes that share a file in the
+// state dir (the WebService budget, the hook state) with an exclusive lock
+// on a lock file next to it: flock on Unix, LockFileEx on Windows.
+package lockfile
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Lock blocks until it holds the lock on path, creating the file and its
+// directory if needed, and returns the function that releases it.
+func Lock(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lock(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking %s: %w", filepath.Base(path), err)
+	}
+	return func() {
+		unlock(f)
+		f.Close()
+	}, nil
+}
@@ -0,0 +1,19 @@
+//go:build windows
+
+package lockfile
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lock locks the first byte of f; LockFileEx locks byte ranges, and every
+// process locks the same one.
+func lock(f *os.File) error {
+	return windows.LockFileEx(windows.Handle(f.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK, 0, 1, 0, &windows.Overlapped{})
+}
+
+func unlock(f *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(f.Fd()), 0, 1, 0, &windows.Overlapped{})
+}
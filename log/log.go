@@ -1,22 +1,45 @@
 package log
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"strings"
+	"sync"
 	"time"
 )
 
 type LogLevel int
 
+// Format selects how log lines are written.
+type Format int
+
+const (
+	// FormatText writes "[time] LEVEL: message key=value ...".
+	FormatText Format = iota
+	// FormatJSON writes one JSON object per line with time, level, msg and
+	// the fields.
+	FormatJSON
+)
+
 // Logger interface
 type Logger struct {
-	level      LogLevel
-	writer     io.Writer
+	// sink is shared by a logger and the loggers derived from it with With,
+	// so level, writer and format changes apply to all of them.
+	sink       *sink
+	fields     []any
 	timeFunc   func() time.Time
 	levelNames []string
 }
 
+type sink struct {
+	mu     sync.Mutex
+	level  LogLevel
+	writer io.Writer
+	format Format
+}
+
 const (
 	Debug   LogLevel = 0
 	Info    LogLevel = 1
@@ -30,9 +53,9 @@ var logLevelNames = []string{"DEBUG", "INFO", "WARNING", "ERROR"}
 var logger Logger
 
 func init() {
+	// Logs go to stderr so they never mix with command output on stdout.
 	logger = Logger{
-		level:      Info,
-		writer:     os.Stdout,
+		sink:       &sink{level: Info, writer: os.Stderr},
 		timeFunc:   time.Now,
 		levelNames: logLevelNames,
 	}
@@ -41,13 +64,25 @@ func init() {
 // NewLogger creates a new logger instance
 func NewLogger(level LogLevel, writer io.Writer) *Logger {
 	return &Logger{
-		level:      level,
-		writer:     writer,
+		sink:       &sink{level: level, writer: writer},
 		timeFunc:   time.Now,
 		levelNames: logLevelNames,
 	}
 }
 
+// Default returns the package-level logger used by the Infof etc. functions.
+func Default() *Logger {
+	return &logger
+}
+
+// With returns a logger that adds the key/value pairs to every line, e.g.
+// With("survey", 4609, "code", uniqueCode). It shares l's level and writer.
+func (l *Logger) With(keyvals ...any) *Logger {
+	child := *l
+	child.fields = append(append([]any(nil), l.fields...), keyvals...)
+	return &child
+}
+
 // Log functions for different levels
 
 func (l *Logger) Debugf(format string, args ...any) {
@@ -82,26 +117,124 @@ func Errorf(format string, args ...any) {
 	logger.log(Error, format, args...)
 }
 
+// With returns a child of the package-level logger with the given fields.
+func With(keyvals ...any) *Logger {
+	return logger.With(keyvals...)
+}
+
 // log is the internal function to log messages
 func (l *Logger) log(level LogLevel, format string, args ...any) {
-	if level >= l.level {
-		stamp := l.timeFunc().Format(time.RFC3339Nano)
-		fmt.Fprintf(l.writer, "["+stamp+"] "+l.levelNames[level]+": "+format+"\n", args...)
+	s := l.sink
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if level < s.level {
+		return
+	}
+	stamp := l.timeFunc()
+	msg := fmt.Sprintf(format, args...)
+	if s.format == FormatJSON {
+		fmt.Fprintln(s.writer, l.jsonLine(stamp, level, msg))
+		return
 	}
+	line := "[" + stamp.Format(time.RFC3339Nano) + "] " + l.levelNames[level] + ": " + msg
+	for i := 0; i < len(l.fields); i += 2 {
+		line += " " + fmt.Sprint(l.fields[i]) + "=" + textValue(field(l.fields, i+1))
+	}
+	fmt.Fprintln(s.writer, line)
+}
+
+func (l *Logger) jsonLine(stamp time.Time, level LogLevel, msg string) string {
+	// Build the object by hand to keep time, level and msg first.
+	var b strings.Builder
+	b.WriteString(`{"time":`)
+	writeJSON(&b, stamp.Format(time.RFC3339Nano))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, l.levelNames[level])
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, msg)
+	for i := 0; i < len(l.fields); i += 2 {
+		b.WriteByte(',')
+		writeJSON(&b, fmt.Sprint(l.fields[i]))
+		b.WriteByte(':')
+		writeJSON(&b, field(l.fields, i+1))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func field(fields []any, i int) any {
+	if i >= len(fields) {
+		return "(missing)"
+	}
+	v := fields[i]
+	switch v := v.(type) {
+	case time.Duration:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+	return v
+}
+
+func writeJSON(b *strings.Builder, v any) {
+	enc, err := json.Marshal(v)
+	if err != nil {
+		enc, _ = json.Marshal(fmt.Sprint(v))
+	}
+	b.Write(enc)
+}
+
+func textValue(v any) string {
+	s := fmt.Sprint(v)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
 }
 
 func (l *Logger) SetLogWriter(writer io.Writer) {
-	l.writer = writer
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	l.sink.writer = writer
 }
 
 func (l *Logger) SetLogLevel(level LogLevel) {
-	l.level = level
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	l.sink.level = level
 }
 
 func (l *Logger) GetLogLevel() LogLevel {
-	return l.level
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	return l.sink.level
+}
+
+func (l *Logger) SetFormat(format Format) {
+	l.sink.mu.Lock()
+	defer l.sink.mu.Unlock()
+	l.sink.format = format
 }
 
 func SetLogLevel(level LogLevel) {
-	logger.level = level
-}
\ No newline at end of file
+	logger.SetLogLevel(level)
+}
+
+func SetLogWriter(writer io.Writer) {
+	logger.SetLogWriter(writer)
+}
+
+func SetFormat(format Format) {
+	logger.SetFormat(format)
+}
+
+// ParseFormat maps "text" or "json" to a Format.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "", "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	}
+	return FormatText, fmt.Errorf("unknown log format %q (use text or json)", s)
+}
@@ -0,0 +1,81 @@
+package log
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+// RotatingFile is an io.Writer that appends to a file and rotates it when it
+// would grow beyond MaxBytes: path becomes path.1, path.1 becomes path.2 and
+// so on, keeping at most MaxBackups old files.
+type RotatingFile struct {
+	Path       string
+	MaxBytes   int64
+	MaxBackups int
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+}
+
+// OpenRotatingFile opens (or creates) path for appending.
+func OpenRotatingFile(path string, maxBytes int64, maxBackups int) (*RotatingFile, error) {
+	r := &RotatingFile{Path: path, MaxBytes: maxBytes, MaxBackups: maxBackups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("opening log file: %w", err)
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.MaxBytes > 0 && r.size > 0 && r.size+int64(len(p)) > r.MaxBytes {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	if r.MaxBackups > 0 {
+		os.Remove(fmt.Sprintf("%s.%d", r.Path, r.MaxBackups))
+		for i := r.MaxBackups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.Path, i), fmt.Sprintf("%s.%d", r.Path, i+1))
+		}
+		if err := os.Rename(r.Path, r.Path+".1"); err != nil {
+			return err
+		}
+	} else if err := os.Truncate(r.Path, 0); err != nil {
+		return err
+	}
+	return r.open()
+}
+
+// Close closes the current file.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
@@ -0,0 +1,233 @@
+// Package metrics collects counters and histograms and writes them in the
+// Prometheus text exposition format, for a /metrics endpoint in daemon modes
+// or a node_exporter textfile after one-shot runs.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefBuckets are histogram buckets in seconds, from fast DB queries up to
+// slow result exports.
+var DefBuckets = []float64{0.005, 0.025, 0.1, 0.25, 1, 2.5, 5, 10, 30, 60, 120, 300}
+
+type collector interface {
+	write(w io.Writer)
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []collector
+)
+
+func register(c collector) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, c)
+}
+
+type desc struct {
+	name   string
+	help   string
+	labels []string
+}
+
+func (d desc) key(values []string) string {
+	if len(values) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(values)))
+	}
+	return strings.Join(values, "\x00")
+}
+
+// labelPairs formats {a="x",b="y"}, with extra appended (e.g. le).
+func (d desc) labelPairs(key string, extra ...string) string {
+	var pairs []string
+	if len(d.labels) > 0 {
+		for i, v := range strings.Split(key, "\x00") {
+			pairs = append(pairs, d.labels[i]+`="`+escape(v)+`"`)
+		}
+	}
+	pairs = append(pairs, extra...)
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func (d desc) header(w io.Writer, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, d.help, d.name, kind)
+}
+
+func escape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatValue(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Counter is a monotonically increasing value per label set.
+type Counter struct {
+	desc
+	mu     sync.Mutex
+	values map[string]float64
+}
+
+// NewCounter registers a counter with the given label names.
+func NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name, help, labels}, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+// Inc adds 1 for the label values.
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds v (>= 0) for the label values.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	k := c.key(labelValues)
+	c.mu.Lock()
+	c.values[k] += v
+	c.mu.Unlock()
+}
+
+func (c *Counter) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.header(w, "counter")
+	for _, k := range sortedKeys(c.values) {
+		fmt.Fprintf(w, "%s%s %s\n", c.name, c.labelPairs(k), formatValue(c.values[k]))
+	}
+}
+
+// Histogram counts observations in cumulative buckets per label set.
+type Histogram struct {
+	desc
+	buckets []float64
+	mu      sync.Mutex
+	series  map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram registers a histogram with the given buckets (DefBuckets if
+// nil) and label names.
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if buckets == nil {
+		buckets = DefBuckets
+	}
+	h := &Histogram{desc: desc{name, help, labels}, buckets: buckets, series: make(map[string]*histogramSeries)}
+	register(h)
+	return h
+}
+
+// Observe records one value for the label values.
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	k := h.key(labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := h.series[k]
+	if s == nil {
+		s = &histogramSeries{counts: make([]uint64, len(h.buckets))}
+		h.series[k] = s
+	}
+	for i, b := range h.buckets {
+		if v <= b {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+// Since records the seconds elapsed since start.
+func (h *Histogram) Since(start time.Time, labelValues ...string) {
+	h.Observe(time.Since(start).Seconds(), labelValues...)
+}
+
+func (h *Histogram) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.header(w, "histogram")
+	for _, k := range sortedKeys(h.series) {
+		s := h.series[k]
+		for i, b := range h.buckets {
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(k, `le="`+formatValue(b)+`"`), s.counts[i])
+		}
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, h.labelPairs(k, `le="+Inf"`), s.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.name, h.labelPairs(k), formatValue(s.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, h.labelPairs(k), s.count)
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// WriteText writes all registered metrics in the text exposition format.
+func WriteText(w io.Writer) error {
+	registryMu.Lock()
+	collectors := append([]collector(nil), registry...)
+	registryMu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+// Handler serves the metrics, for mounting at /metrics.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteText(w)
+	})
+}
+
+// WriteTextfile writes the metrics to path atomically, for the node_exporter
+// textfile collector (the file name must end in .prom).
+func WriteTextfile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".metrics-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := WriteText(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
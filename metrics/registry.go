@@ -0,0 +1,40 @@
+package metrics
+
+// The metrics recorded by eusurveymgr.
+var (
+	HTTPRequests = NewCounter("eusurveymgr_http_requests_total",
+		"Requests to the EUSurvey server by endpoint and HTTP status (\"error\" if no response).",
+		"endpoint", "status")
+	HTTPDuration = NewHistogram("eusurveymgr_http_request_duration_seconds",
+		"Duration of requests to the EUSurvey server by endpoint.", nil, "endpoint")
+	Logins = NewCounter("eusurveymgr_logins_total",
+		"Session logins to the EUSurvey server by result.", "result")
+
+	ExportPollDuration = NewHistogram("eusurveymgr_export_poll_duration_seconds",
+		"Time from the first getResults poll until the export was ready or failed, by result.", nil, "result")
+	ExportPollAttempts = NewCounter("eusurveymgr_export_poll_attempts_total",
+		"getResults polls made while waiting for exports.")
+	PDFGeneration = NewHistogram("eusurveymgr_pdf_generation_seconds",
+		"Time to obtain an answer PDF (readiness check, generation, download), by result.", nil, "result")
+
+	DBQueryDuration = NewHistogram("eusurveymgr_db_query_duration_seconds",
+		"Duration of database queries by query.", nil, "query")
+
+	NewResponses = NewCounter("eusurveymgr_new_responses_total",
+		"New answer sets seen by pollers (db answers --follow, hooks) by survey.", "survey")
+	HookDeliveries = NewCounter("eusurveymgr_hook_deliveries_total",
+		"Hook delivery attempts by hook and result.", "hook", "result")
+
+	JobRuns = NewCounter("eusurveymgr_job_runs_total",
+		"Scheduled job runs by job and status (ok, failed, skipped).", "job", "status")
+	JobDuration = NewHistogram("eusurveymgr_job_duration_seconds",
+		"Duration of scheduled job runs by job.", nil, "job")
+)
+
+// Result returns the result label for an error: "ok" or "error".
+func Result(err error) string {
+	if err != nil {
+		return "error"
+	}
+	return "ok"
+}
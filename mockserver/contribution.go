@@ -0,0 +1,77 @@
+package mockserver
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+)
+
+// contributionCSS mimics the EUSurvey answer stylesheet; the background
+// image is resolved relative to the stylesheet, as on the real server.
+const contributionCSS = `body { font-family: sans-serif; margin: 2em; }
+.header { background: url(../images/logo.png) no-repeat left center; padding-left: 40px; min-height: 32px; }
+.question { font-weight: bold; margin-top: 1em; }
+.answer { margin-left: 1em; color: #004494; }
+`
+
+// logoPNG is a 32x32 EU-blue square.
+var logoPNG = func() []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for y := 0; y < 32; y++ {
+		for x := 0; x < 32; x++ {
+			img.Set(x, y, color.RGBA{0x00, 0x44, 0x94, 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}()
+
+// contribution renders an answer set like /preparecontribution, with a
+// relative stylesheet link and an absolute image path.
+func (s *Server) contribution(w http.ResponseWriter, r *http.Request, code string) {
+	survey, as := s.answerSet(code)
+	if as == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html><head>
+<meta charset="utf-8"/>
+<title>%s</title>
+<link href="../resources/css/contribution.css" rel="stylesheet" type="text/css"/>
+</head><body>
+<div class="header"><img src="/resources/images/logo.png" alt="EUSurvey"/> <h1>%[1]s</h1></div>
+<p>Contribution %s, %s</p>
+`, html.EscapeString(survey.Title), html.EscapeString(as.UniqueCode), html.EscapeString(as.Date))
+	titles := fixtureTitles(survey)
+	for _, a := range as.Answers {
+		value := a.Value
+		if a.PA_UID != "" {
+			value = titles[a.PA_UID]
+		}
+		fmt.Fprintf(w, "<div class=\"question\">%s</div>\n<div class=\"answer\">%s</div>\n",
+			titles[a.QuestionUID], html.EscapeString(value))
+	}
+	fmt.Fprint(w, "</body></html>\n")
+}
+
+// resource serves the static files referenced by the contribution page.
+// Like on the real server they need no login.
+func resource(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/resources/css/contribution.css":
+		w.Header().Set("Content-Type", "text/css")
+		fmt.Fprint(w, contributionCSS)
+	case "/resources/images/logo.png":
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(logoPNG)
+	default:
+		http.NotFound(w, r)
+	}
+}
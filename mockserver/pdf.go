@@ -0,0 +1,63 @@
+package mockserver
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// simplePDF renders a valid one-page PDF showing the given lines in
+// Helvetica. Characters outside Latin-1 are replaced with '?'; the mock only
+// needs something PDF readers and merge tools accept.
+func simplePDF(lines ...string) []byte {
+	var content bytes.Buffer
+	content.WriteString("BT /F1 12 Tf 14 TL 56 780 Td\n")
+	for _, line := range lines {
+		fmt.Fprintf(&content, "(%s) Tj T*\n", pdfString(line))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// pdfString escapes s for a PDF literal string in WinAnsi encoding.
+func pdfString(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20:
+			b.WriteByte(' ')
+		case r < 0x80:
+			b.WriteRune(r)
+		case r <= 0xFF:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,471 @@
+// Package mockserver is a local stand-in for an EUSurvey instance. It serves
+// the endpoints used by the client package (Basic-Auth webservice, CSRF form
+// login, async results export, answer PDF generation, rendered contributions) from fixture data, with
+// configurable delays and failure injection.
+package mockserver
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed demo.json
+var demoFixture []byte
+
+// DemoFixture returns the built-in demo survey data.
+func DemoFixture() *db.Fixture {
+	var f db.Fixture
+	if err := json.Unmarshal(demoFixture, &f); err != nil {
+		panic(err)
+	}
+	return &f
+}
+
+type Options struct {
+	// Credentials accepted for Basic Auth and the login form.
+	Username string
+	Password string
+	// ExportDelay is how long getResults answers 204 before the export is done.
+	ExportDelay time.Duration
+	// PDFDelay is how long after createanswerpdf the PDF becomes ready.
+	PDFDelay time.Duration
+	// Latency is added to every request.
+	Latency time.Duration
+	// FailureRate is the probability (0..1) that any request fails with 500.
+	FailureRate float64
+	// FailEndpoints always fail with 500, by endpoint name (e.g.
+	// "createanswerpdf", "getResults", "login").
+	FailEndpoints []string
+	// MaxRequestsPerDay limits /webservice calls like
+	// webservice.maxrequestsperday; 0 means unlimited.
+	MaxRequestsPerDay int
+	// SessionTTL ends a login session that long after it logged in, like
+	// an EUSurvey session timeout; 0 means never.
+	SessionTTL time.Duration
+}
+
+type exportTask struct {
+	survey  *db.FixtureSurvey
+	started time.Time
+}
+
+type session struct {
+	csrf          string
+	authenticated bool
+	loggedIn      time.Time
+}
+
+// Server implements http.Handler. Use New, or NewTestServer in tests.
+type Server struct {
+	fixture *db.Fixture
+	opts    Options
+
+	mu         sync.Mutex
+	sessions   map[string]*session
+	tasks      map[string]*exportTask
+	pdfs       map[string]time.Time
+	nextTask   int
+	day        string
+	wsRequests int
+	rnd        *mathrand.Rand
+}
+
+func New(f *db.Fixture, opts Options) *Server {
+	if opts.Username == "" {
+		opts.Username = "root"
+	}
+	if opts.Password == "" {
+		opts.Password = "secret"
+	}
+	return &Server{
+		fixture:  f,
+		opts:     opts,
+		sessions: make(map[string]*session),
+		tasks:    make(map[string]*exportTask),
+		pdfs:     make(map[string]time.Time),
+		rnd:      mathrand.New(mathrand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// NewTestServer starts a Server on a loopback port. Point the client's
+// base_url at the returned server's URL and Close it when done.
+func NewTestServer(f *db.Fixture, opts Options) *httptest.Server {
+	return httptest.NewServer(New(f, opts))
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("MOCK -- %s %s", r.Method, r.URL.Path)
+	if s.opts.Latency > 0 {
+		time.Sleep(s.opts.Latency)
+	}
+
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	endpoint := parts[len(parts)-1]
+	if len(parts) > 1 && parts[0] != "preparecontribution" {
+		endpoint = parts[1]
+	}
+	if s.shouldFail(endpoint) {
+		http.Error(w, "Injected failure", http.StatusInternalServerError)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/auth/login" && r.Method == http.MethodGet:
+		s.loginPage(w, r)
+	case r.URL.Path == "/login" && r.Method == http.MethodPost:
+		s.login(w, r)
+	case parts[0] == "webservice" && len(parts) >= 2:
+		s.webservice(w, r, parts[1], parts[2:])
+	case len(parts) == 3 && parts[0] == "worker" && parts[1] == "createanswerpdf":
+		s.withSession(w, r, func() { s.createAnswerPDF(w, parts[2]) })
+	case len(parts) == 3 && parts[0] == "pdf" && parts[1] == "answerready":
+		s.withSession(w, r, func() { s.answerReady(w, parts[2]) })
+	case len(parts) == 3 && parts[0] == "pdf" && parts[1] == "answer":
+		s.withSession(w, r, func() { s.answerPDF(w, parts[2]) })
+	case len(parts) == 2 && parts[0] == "preparecontribution":
+		s.withSession(w, r, func() { s.contribution(w, r, parts[1]) })
+	case parts[0] == "resources":
+		resource(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) shouldFail(endpoint string) bool {
+	for _, e := range s.opts.FailEndpoints {
+		if strings.EqualFold(e, endpoint) {
+			return true
+		}
+	}
+	if s.opts.FailureRate <= 0 {
+		return false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.rnd.Float64() < s.opts.FailureRate
+}
+
+// Session login
+
+func (s *Server) session(w http.ResponseWriter, r *http.Request) *session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c, err := r.Cookie("JSESSIONID"); err == nil {
+		if sess, ok := s.sessions[c.Value]; ok {
+			return sess
+		}
+	}
+	id := randomHex(16)
+	sess := &session{csrf: randomHex(18)}
+	s.sessions[id] = sess
+	http.SetCookie(w, &http.Cookie{Name: "JSESSIONID", Value: id, Path: "/", HttpOnly: true})
+	return sess
+}
+
+func (s *Server) loginPage(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(w, r)
+	w.Header().Set("Content-Type", "text/html;charset=UTF-8")
+	fmt.Fprintf(w, `<!DOCTYPE html>
+<html><head>
+<meta name="_csrf" content="%s"/>
+<meta name="_csrf_header" content="X-CSRF-TOKEN"/>
+<title>EUSurvey - Login</title>
+</head><body><form method="POST" action="login"></form></body></html>`, sess.csrf)
+}
+
+// login mimics Spring Security: success and failure both answer 302, only
+// the redirect target differs.
+func (s *Server) login(w http.ResponseWriter, r *http.Request) {
+	sess := s.session(w, r)
+	if r.FormValue("_csrf") != sess.csrf {
+		http.Error(w, "Invalid CSRF Token", http.StatusForbidden)
+		return
+	}
+	if r.FormValue("username") != s.opts.Username || r.FormValue("password") != s.opts.Password {
+		http.Redirect(w, r, "/auth/login?error=true", http.StatusFound)
+		return
+	}
+	s.mu.Lock()
+	sess.authenticated = true
+	sess.loggedIn = time.Now()
+	s.mu.Unlock()
+	http.Redirect(w, r, "/dashboard", http.StatusFound)
+}
+
+func (s *Server) withSession(w http.ResponseWriter, r *http.Request, handler func()) {
+	sess := s.session(w, r)
+	s.mu.Lock()
+	if s.opts.SessionTTL > 0 && time.Since(sess.loggedIn) >= s.opts.SessionTTL {
+		sess.authenticated = false
+	}
+	ok := sess.authenticated
+	s.mu.Unlock()
+	if !ok {
+		http.Redirect(w, r, "/auth/login", http.StatusFound)
+		return
+	}
+	handler()
+}
+
+// Answer PDFs
+
+func (s *Server) answerSet(code string) (*db.FixtureSurvey, *db.FixtureAnswerSet) {
+	for i := range s.fixture.Surveys {
+		survey := &s.fixture.Surveys[i]
+		for j := range survey.AnswerSets {
+			if survey.AnswerSets[j].UniqueCode == code {
+				return survey, &survey.AnswerSets[j]
+			}
+		}
+	}
+	return nil, nil
+}
+
+func (s *Server) createAnswerPDF(w http.ResponseWriter, code string) {
+	if _, as := s.answerSet(code); as == nil {
+		fmt.Fprint(w, "error")
+		return
+	}
+	s.mu.Lock()
+	if _, ok := s.pdfs[code]; !ok {
+		s.pdfs[code] = time.Now()
+	}
+	s.mu.Unlock()
+	fmt.Fprint(w, "OK")
+}
+
+func (s *Server) pdfReady(code string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	started, ok := s.pdfs[code]
+	return ok && time.Since(started) >= s.opts.PDFDelay
+}
+
+func (s *Server) answerReady(w http.ResponseWriter, code string) {
+	if s.pdfReady(code) {
+		fmt.Fprint(w, "exists")
+		return
+	}
+	fmt.Fprint(w, "notready")
+}
+
+func (s *Server) answerPDF(w http.ResponseWriter, code string) {
+	survey, as := s.answerSet(code)
+	if as == nil || !s.pdfReady(code) {
+		http.NotFound(w, nil)
+		return
+	}
+	lines := []string{survey.Title, "Contribution " + as.UniqueCode, as.Date, ""}
+	titles := fixtureTitles(survey)
+	for _, a := range as.Answers {
+		value := a.Value
+		if a.PA_UID != "" {
+			value = titles[a.PA_UID]
+		}
+		lines = append(lines, titles[a.QuestionUID]+": "+value)
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Write(simplePDF(lines...))
+}
+
+func fixtureTitles(survey *db.FixtureSurvey) map[string]string {
+	titles := make(map[string]string)
+	for _, e := range survey.Elements {
+		titles[e.UID] = e.Title
+		for _, o := range slices.Concat(e.Options, e.Rows) {
+			titles[o.UID] = o.Title
+		}
+	}
+	return titles
+}
+
+// WebService API (Basic Auth)
+
+func (s *Server) webservice(w http.ResponseWriter, r *http.Request, method string, args []string) {
+	user, pass, ok := r.BasicAuth()
+	if !ok || user != s.opts.Username || pass != s.opts.Password {
+		w.Header().Set("WWW-Authenticate", `Basic realm="EUSurvey"`)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if !s.countRequest() {
+		http.Error(w, "Maximum number of requests per day reached", http.StatusTooManyRequests)
+		return
+	}
+
+	switch {
+	case method == "getMySurveys":
+		s.getMySurveys(w)
+	case method == "getSurveyMetadata" && len(args) == 1:
+		s.getSurveyMetadata(w, r, args[0])
+	case method == "getSurveyPDF" && len(args) == 1:
+		survey := s.surveyByAlias(args[0])
+		if survey == nil {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Write(simplePDF(survey.Title, "Survey form "+survey.Alias))
+	case method == "prepareResults" && len(args) == 2:
+		s.prepareResults(w, args[0])
+	case method == "getResults" && len(args) == 1:
+		s.getResults(w, args[0])
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) countRequest() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	today := time.Now().Format("2006-01-02")
+	if s.day != today {
+		s.day = today
+		s.wsRequests = 0
+	}
+	s.wsRequests++
+	return s.opts.MaxRequestsPerDay <= 0 || s.wsRequests <= s.opts.MaxRequestsPerDay
+}
+
+func (s *Server) surveyByAlias(alias string) *db.FixtureSurvey {
+	for i := range s.fixture.Surveys {
+		survey := &s.fixture.Surveys[i]
+		if survey.Alias == alias || strconv.FormatInt(survey.ID, 10) == alias {
+			return survey
+		}
+	}
+	return nil
+}
+
+func writeXML(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/xml;charset=UTF-8")
+	w.Write([]byte(xml.Header))
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	enc.Encode(v)
+}
+
+func (s *Server) getMySurveys(w http.ResponseWriter) {
+	type survey struct {
+		UID   string `xml:"uid,attr"`
+		Alias string `xml:"alias,attr"`
+		Title string `xml:"Title"`
+	}
+	list := struct {
+		XMLName xml.Name `xml:"Surveys"`
+		User    string   `xml:"user,attr"`
+		Surveys []survey `xml:"Survey"`
+	}{User: s.opts.Username}
+	for _, f := range s.fixture.Surveys {
+		list.Surveys = append(list.Surveys, survey{UID: f.UID, Alias: f.Alias, Title: f.Title})
+	}
+	writeXML(w, list)
+}
+
+func (s *Server) getSurveyMetadata(w http.ResponseWriter, r *http.Request, alias string) {
+	survey := s.surveyByAlias(alias)
+	if survey == nil {
+		http.NotFound(w, r)
+		return
+	}
+	status := "unpublished"
+	if survey.Published {
+		status = "published"
+	}
+	meta := struct {
+		XMLName    xml.Name `xml:"Survey"`
+		ID         int64    `xml:"id,attr"`
+		Alias      string   `xml:"alias,attr"`
+		SurveyType string   `xml:"SurveyType"`
+		Title      string   `xml:"Title"`
+		Language   string   `xml:"PivotLanguage"`
+		Contact    string   `xml:"Contact"`
+		Status     string   `xml:"Status"`
+		Start      string   `xml:"Start"`
+		End        string   `xml:"End"`
+		Results    int      `xml:"Results"`
+		Security   string   `xml:"Security"`
+		Visibility string   `xml:"Visibility"`
+	}{
+		ID: survey.ID, Alias: survey.Alias, SurveyType: "Standard", Title: survey.Title,
+		Language: "RO", Contact: "contact@example.com", Status: status,
+		Start: "Unset", End: "Unset", Results: len(survey.AnswerSets),
+		Security: "open", Visibility: "private",
+	}
+	writeXML(w, meta)
+}
+
+// prepareResults always accepts the request (201 + task ID); unknown surveys
+// surface as 412 from getResults, as on the real server.
+func (s *Server) prepareResults(w http.ResponseWriter, formID string) {
+	s.mu.Lock()
+	s.nextTask++
+	id := strconv.Itoa(s.nextTask)
+	s.tasks[id] = &exportTask{survey: s.surveyByAlias(formID), started: time.Now()}
+	s.mu.Unlock()
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprint(w, id)
+}
+
+func (s *Server) getResults(w http.ResponseWriter, taskID string) {
+	s.mu.Lock()
+	task, ok := s.tasks[taskID]
+	s.mu.Unlock()
+	if !ok || task.survey == nil || len(task.survey.AnswerSets) == 0 {
+		w.WriteHeader(http.StatusPreconditionFailed)
+		return
+	}
+	if time.Since(task.started) < s.opts.ExportDelay {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	type answer struct {
+		QuestionUID string `xml:"questionUid,attr"`
+		Value       string `xml:",chardata"`
+	}
+	type answerSet struct {
+		ID         int64    `xml:"id,attr"`
+		UniqueCode string   `xml:"uniqueCode,attr"`
+		Date       string   `xml:"date,attr"`
+		Answers    []answer `xml:"Answer"`
+	}
+	results := struct {
+		XMLName    xml.Name    `xml:"Results"`
+		Survey     string      `xml:"survey,attr"`
+		AnswerSets []answerSet `xml:"AnswerSet"`
+	}{Survey: task.survey.Alias}
+	titles := fixtureTitles(task.survey)
+	for _, as := range task.survey.AnswerSets {
+		set := answerSet{ID: as.ID, UniqueCode: as.UniqueCode, Date: as.Date}
+		for _, a := range as.Answers {
+			value := a.Value
+			if a.PA_UID != "" {
+				value = titles[a.PA_UID]
+			}
+			set.Answers = append(set.Answers, answer{QuestionUID: a.QuestionUID, Value: value})
+		}
+		results.AnswerSets = append(results.AnswerSets, set)
+	}
+	writeXML(w, results)
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,460 @@
+// Package output renders command results as aligned tables, JSON, NDJSON,
+// CSV, YAML or Go text/template output.
+//
+// Commands describe their records once as a list of columns; the renderer
+// takes care of column selection (--columns), headers, rune-aware width
+// truncation and the serialisation of each format.
+package output
+
+import (
+	"database/sql/driver"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"text/template"
+	"time"
+	"unicode/utf8"
+)
+
+// Format selects how records are written.
+type Format string
+
+const (
+	Table    Format = "table"
+	JSON     Format = "json"
+	NDJSON   Format = "ndjson"
+	CSV      Format = "csv"
+	YAML     Format = "yaml"
+	Template Format = "template"
+)
+
+// Formats lists the supported formats in the order they are documented.
+var Formats = []Format{Table, JSON, NDJSON, CSV, YAML, Template}
+
+// ParseFormat converts a --format value.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown output format %q (use %s)", s, strings.Join(names, ", "))
+}
+
+// Options are the user's output choices.
+type Options struct {
+	Format Format
+	// Columns selects and orders the columns; empty means the defaults.
+	Columns   []string
+	NoHeaders bool
+	// Template is executed once per record when Format is Template.
+	Template string
+}
+
+// Structured reports whether the format can represent nested data, so
+// commands with report-shaped results can encode them whole.
+func (o Options) Structured() bool {
+	return o.Format == JSON || o.Format == YAML
+}
+
+// Column describes one field of a record.
+type Column[T any] struct {
+	// Name is the key in JSON, YAML, CSV headers and templates, and what
+	// --columns refers to. Table headers are the upper-cased name.
+	Name string
+	// Width truncates table cells to this many characters (0: no limit).
+	Width int
+	// Hidden columns are left out of the default table and CSV columns but
+	// are still part of the structured formats and templates.
+	Hidden bool
+	Value  func(T) any
+}
+
+// Printer writes records one at a time. Table output is aligned when the
+// printer is closed, unless it was created with NewStream.
+type Printer[T any] struct {
+	w      io.Writer
+	opts   Options
+	cols   []Column[T]
+	stream bool
+
+	tmpl    *template.Template
+	tw      *tabwriter.Writer
+	csv     *csv.Writer
+	header  bool
+	records []record
+}
+
+// NewPrinter validates opts against cols and returns a printer writing to w.
+func NewPrinter[T any](w io.Writer, opts Options, cols []Column[T]) (*Printer[T], error) {
+	if opts.Format == "" {
+		opts.Format = Table
+	}
+	selected, err := selectColumns(opts, cols)
+	if err != nil {
+		return nil, err
+	}
+	p := &Printer[T]{w: w, opts: opts, cols: selected}
+	switch opts.Format {
+	case Table:
+		p.tw = tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	case CSV:
+		p.csv = csv.NewWriter(w)
+	case Template:
+		if opts.Template == "" {
+			return nil, fmt.Errorf("--format template needs --template")
+		}
+		p.tmpl, err = template.New("output").Funcs(funcs).Parse(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("parsing --template: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// NewStream is NewPrinter for output that must appear as records arrive:
+// table rows are padded to fixed widths instead of being aligned at the
+// end, and the formats that wrap all records (JSON, YAML) are refused.
+func NewStream[T any](w io.Writer, opts Options, cols []Column[T]) (*Printer[T], error) {
+	if opts.Structured() {
+		return nil, fmt.Errorf("--format %s cannot be streamed, use ndjson", opts.Format)
+	}
+	p, err := NewPrinter(w, opts, cols)
+	if err != nil {
+		return nil, err
+	}
+	p.stream = true
+	p.tw = nil
+	return p, nil
+}
+
+// Write renders one record.
+func (p *Printer[T]) Write(row T) error {
+	rec := p.record(row)
+	switch p.opts.Format {
+	case Table:
+		p.writeHeader()
+		cells := make([]string, len(rec.values))
+		for i, v := range rec.values {
+			cells[i] = Truncate(cell(v), p.cols[i].Width)
+		}
+		return p.writeTableRow(cells)
+	case CSV:
+		p.writeHeader()
+		cells := make([]string, len(rec.values))
+		for i, v := range rec.values {
+			cells[i] = text(v)
+		}
+		p.csv.Write(cells)
+		if p.stream {
+			p.csv.Flush()
+		}
+		return p.csv.Error()
+	case NDJSON:
+		b, err := json.Marshal(rec)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", b)
+		return err
+	case Template:
+		if err := p.tmpl.Execute(p.w, rec.Map()); err != nil {
+			return fmt.Errorf("executing --template: %w", err)
+		}
+		_, err := io.WriteString(p.w, "\n")
+		return err
+	}
+	p.records = append(p.records, rec)
+	return nil
+}
+
+// Close writes whatever the format keeps until the end: the aligned table,
+// the JSON array or the YAML sequence. Tables and CSV always get their
+// header, even without records.
+func (p *Printer[T]) Close() error {
+	switch p.opts.Format {
+	case Table:
+		p.writeHeader()
+		if p.tw != nil {
+			return p.tw.Flush()
+		}
+	case CSV:
+		p.writeHeader()
+		p.csv.Flush()
+		return p.csv.Error()
+	case JSON, YAML:
+		records := p.records
+		if records == nil {
+			records = []record{}
+		}
+		return Encode(p.w, p.opts.Format, records)
+	}
+	return nil
+}
+
+// Render writes rows with the given columns.
+func Render[T any](w io.Writer, opts Options, cols []Column[T], rows []T) error {
+	p, err := NewPrinter(w, opts, cols)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := p.Write(row); err != nil {
+			return err
+		}
+	}
+	return p.Close()
+}
+
+// RenderRecord writes a single record, for detail commands. A table lists
+// one field per line; JSON and YAML produce an object instead of a list.
+func RenderRecord[T any](w io.Writer, opts Options, cols []Column[T], row T) error {
+	p, err := NewPrinter(w, opts, cols)
+	if err != nil {
+		return err
+	}
+	switch p.opts.Format {
+	case Table:
+		rec := p.record(row)
+		for i, v := range rec.values {
+			fmt.Fprintf(p.tw, "%s\t%s\n", strings.ToUpper(p.cols[i].Name), cell(v))
+		}
+		return p.tw.Flush()
+	case JSON, YAML:
+		return Encode(w, p.opts.Format, p.record(row))
+	}
+	if err := p.Write(row); err != nil {
+		return err
+	}
+	return p.Close()
+}
+
+// Encode writes v as indented JSON or as YAML. It is meant for results that
+// are not flat records, such as reports with nested sections.
+func Encode(w io.Writer, format Format, v any) error {
+	switch format {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		return writeYAML(w, b)
+	}
+	return fmt.Errorf("--format %s cannot encode nested data", format)
+}
+
+// Truncate shortens s to at most width characters, ending in "…" when cut.
+// It counts runes, so multi-byte characters are never split.
+func Truncate(s string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:width-1]) + "…"
+}
+
+// ColumnNames returns the names of cols, for help texts.
+func ColumnNames[T any](cols []Column[T]) string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+func selectColumns[T any](opts Options, cols []Column[T]) ([]Column[T], error) {
+	if len(opts.Columns) == 0 {
+		if opts.Format != Table && opts.Format != CSV {
+			return cols, nil
+		}
+		var selected []Column[T]
+		for _, c := range cols {
+			if !c.Hidden {
+				selected = append(selected, c)
+			}
+		}
+		return selected, nil
+	}
+	var selected []Column[T]
+	for _, name := range opts.Columns {
+		i := indexColumn(cols, strings.TrimSpace(name))
+		if i < 0 {
+			return nil, fmt.Errorf("unknown column %q (available: %s)", name, ColumnNames(cols))
+		}
+		selected = append(selected, cols[i])
+	}
+	return selected, nil
+}
+
+func indexColumn[T any](cols []Column[T], name string) int {
+	for i, c := range cols {
+		if strings.EqualFold(c.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (p *Printer[T]) record(row T) record {
+	rec := record{keys: make([]string, len(p.cols)), values: make([]any, len(p.cols))}
+	for i, c := range p.cols {
+		rec.keys[i] = c.Name
+		rec.values[i] = normalize(c.Value(row))
+	}
+	return rec
+}
+
+func (p *Printer[T]) writeHeader() {
+	if p.header || p.opts.NoHeaders {
+		return
+	}
+	p.header = true
+	names := make([]string, len(p.cols))
+	for i, c := range p.cols {
+		names[i] = c.Name
+	}
+	if p.opts.Format == CSV {
+		p.csv.Write(names)
+		return
+	}
+	for i := range names {
+		names[i] = strings.ToUpper(names[i])
+	}
+	p.writeTableRow(names)
+}
+
+// writeTableRow aligns through the tabwriter, or pads each cell to its
+// column's width when streaming.
+func (p *Printer[T]) writeTableRow(cells []string) error {
+	if !p.stream {
+		_, err := fmt.Fprintln(p.tw, strings.Join(cells, "\t"))
+		return err
+	}
+	var b strings.Builder
+	for i, s := range cells {
+		if i == len(cells)-1 {
+			b.WriteString(s)
+			break
+		}
+		width := max(p.cols[i].Width, len(p.cols[i].Name))
+		b.WriteString(s)
+		b.WriteString(strings.Repeat(" ", max(width-utf8.RuneCountInString(s), 0)+2))
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(p.w, b.String())
+	return err
+}
+
+// record is an ordered set of fields; it marshals to a JSON object with the
+// keys in column order.
+type record struct {
+	keys   []string
+	values []any
+}
+
+func (r record) MarshalJSON() ([]byte, error) {
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range r.keys {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		value, err := json.Marshal(r.values[i])
+		if err != nil {
+			return nil, fmt.Errorf("column %s: %w", k, err)
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return []byte(b.String()), nil
+}
+
+// Map returns the fields by name, as seen by templates.
+func (r record) Map() map[string]any {
+	m := make(map[string]any, len(r.keys))
+	for i, k := range r.keys {
+		m[k] = r.values[i]
+	}
+	return m
+}
+
+// normalize unwraps database NULL wrappers and turns zero times into nil,
+// so every format sees plain values.
+func normalize(v any) any {
+	switch x := v.(type) {
+	case driver.Valuer:
+		value, err := x.Value()
+		if err != nil {
+			return nil
+		}
+		return value
+	case time.Time:
+		if x.IsZero() {
+			return nil
+		}
+	case time.Duration:
+		return x.String()
+	}
+	return v
+}
+
+// text formats a value for CSV and table cells.
+func text(v any) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		return x.Format(time.DateTime)
+	case []string:
+		return strings.Join(x, ", ")
+	case []byte:
+		return string(x)
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Map, reflect.Slice, reflect.Array, reflect.Struct, reflect.Pointer:
+		if b, err := json.Marshal(v); err == nil {
+			return string(b)
+		}
+	}
+	return fmt.Sprint(v)
+}
+
+// cell is text with line breaks and tabs flattened, so a value cannot break
+// the table layout.
+func cell(v any) string {
+	return strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(text(v))
+}
+
+var funcs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"join":     func(sep string, v []string) string { return strings.Join(v, sep) },
+	"truncate": func(width int, v any) string { return Truncate(text(v), width) },
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"text":     text,
+}
@@ -0,0 +1,90 @@
+package output
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"Popescu", 0, "Popescu"},
+		{"Popescu", 7, "Popescu"},
+		{"Popescu", 10, "Popescu"},
+		{"Popescu", 5, "Pope…"},
+		{"Țurcanu Ștefănescu", 8, "Țurcanu…"},
+		{"ăâîșț", 3, "ăâ…"},
+		{"abc", 1, "…"},
+		{"", 3, ""},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.in, tt.width); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
+
+type row struct {
+	ID    int64
+	Name  string
+	Email sql.NullString
+	Date  time.Time
+	Tags  []string
+}
+
+var rowColumns = []Column[row]{
+	{Name: "id", Value: func(r row) any { return r.ID }},
+	{Name: "name", Width: 5, Value: func(r row) any { return r.Name }},
+	{Name: "email", Value: func(r row) any { return r.Email }},
+	{Name: "date", Value: func(r row) any { return r.Date }},
+	{Name: "tags", Hidden: true, Value: func(r row) any { return r.Tags }},
+}
+
+var rows = []row{
+	{ID: 1, Name: "Ana Popescu", Email: sql.NullString{String: "ana@example.com", Valid: true},
+		Date: time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), Tags: []string{"a", "b"}},
+	{ID: 2, Name: "Ion, \"Țurcanu\"\nJr.", Tags: nil},
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+		want string
+	}{
+		{"csv", Options{Format: CSV},
+			"id,name,email,date\n" +
+				"1,Ana Popescu,ana@example.com,2026-03-01 10:00:00\n" +
+				"2,\"Ion, \"\"Țurcanu\"\"\nJr.\",,\n"},
+		{"csv columns", Options{Format: CSV, Columns: []string{"name", "tags"}, NoHeaders: true},
+			"Ana Popescu,\"a, b\"\n" +
+				"\"Ion, \"\"Țurcanu\"\"\nJr.\",\n"},
+		{"ndjson", Options{Format: NDJSON},
+			`{"id":1,"name":"Ana Popescu","email":"ana@example.com","date":"2026-03-01T10:00:00Z","tags":["a","b"]}` + "\n" +
+				`{"id":2,"name":"Ion, \"Țurcanu\"\nJr.","email":null,"date":null,"tags":null}` + "\n"},
+		{"ndjson columns", Options{Format: NDJSON, Columns: []string{"email", "id"}},
+			`{"email":"ana@example.com","id":1}` + "\n" +
+				`{"email":null,"id":2}` + "\n"},
+	}
+	for _, tt := range tests {
+		var b bytes.Buffer
+		if err := Render(&b, tt.opts, rowColumns, rows); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if b.String() != tt.want {
+			t.Errorf("%s: got\n%s\nwant\n%s", tt.name, b.String(), tt.want)
+		}
+	}
+}
+
+func TestRenderUnknownColumn(t *testing.T) {
+	err := Render(&bytes.Buffer{}, Options{Format: CSV, Columns: []string{"phone"}}, rowColumns, rows)
+	if err == nil {
+		t.Fatal("want an error for an unknown column")
+	}
+}
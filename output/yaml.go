@@ -0,0 +1,162 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// node is a JSON value with object keys kept in document order.
+type node struct {
+	scalar any // string, json.Number, bool or nil
+	keys   []string
+	items  []node
+	object bool
+	array  bool
+}
+
+// writeYAML converts a JSON document to block-style YAML. Going through
+// JSON reuses the json tags and marshalers of the encoded types.
+func writeYAML(w io.Writer, data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	n, err := decodeNode(dec)
+	if err != nil {
+		return err
+	}
+	var b strings.Builder
+	emitYAML(&b, n, 0, false)
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func decodeNode(dec *json.Decoder) (node, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return node{}, err
+	}
+	switch tok {
+	case json.Delim('{'):
+		n := node{object: true}
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return node{}, err
+			}
+			value, err := decodeNode(dec)
+			if err != nil {
+				return node{}, err
+			}
+			n.keys = append(n.keys, key.(string))
+			n.items = append(n.items, value)
+		}
+		_, err := dec.Token()
+		return n, err
+	case json.Delim('['):
+		n := node{array: true}
+		for dec.More() {
+			value, err := decodeNode(dec)
+			if err != nil {
+				return node{}, err
+			}
+			n.items = append(n.items, value)
+		}
+		_, err := dec.Token()
+		return n, err
+	}
+	return node{scalar: tok}, nil
+}
+
+// emitYAML writes n at the given indentation. inline means the first line
+// continues after a "- " sequence marker that is already written.
+func emitYAML(b *strings.Builder, n node, indent int, inline bool) {
+	pad := strings.Repeat(" ", indent)
+	switch {
+	case n.object && len(n.items) > 0:
+		for i, key := range n.keys {
+			if i > 0 || !inline {
+				b.WriteString(pad)
+			}
+			b.WriteString(yamlString(key))
+			b.WriteString(":")
+			emitValue(b, n.items[i], indent+2)
+		}
+	case n.array && len(n.items) > 0:
+		for i, item := range n.items {
+			if i > 0 || !inline {
+				b.WriteString(pad)
+			}
+			b.WriteString("- ")
+			if item.scalar != nil || isEmpty(item) {
+				b.WriteString(yamlScalar(item))
+				b.WriteString("\n")
+				continue
+			}
+			emitYAML(b, item, indent+2, true)
+		}
+	default:
+		b.WriteString(yamlScalar(n))
+		b.WriteString("\n")
+	}
+}
+
+// emitValue writes the value of a mapping key: scalars on the same line,
+// collections indented below it.
+func emitValue(b *strings.Builder, n node, indent int) {
+	if (!n.object && !n.array) || isEmpty(n) {
+		b.WriteString(" ")
+		b.WriteString(yamlScalar(n))
+		b.WriteString("\n")
+		return
+	}
+	b.WriteString("\n")
+	emitYAML(b, n, indent, false)
+}
+
+func isEmpty(n node) bool {
+	return (n.object || n.array) && len(n.items) == 0
+}
+
+func yamlScalar(n node) string {
+	switch {
+	case n.object:
+		return "{}"
+	case n.array:
+		return "[]"
+	}
+	switch v := n.scalar.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return fmt.Sprint(v)
+	case json.Number:
+		return v.String()
+	case string:
+		return yamlString(v)
+	}
+	return fmt.Sprint(n.scalar)
+}
+
+// A plain string may not start with "." (.inf, .nan and .5 are floats), a
+// digit or sign, or an indicator such as "@".
+var (
+	plainString = regexp.MustCompile(`^[\p{L}_/][\p{L}\p{N} _./@()+-]*$`)
+	reserved    = map[string]bool{"true": true, "false": true, "yes": true, "no": true,
+		"on": true, "off": true, "y": true, "n": true, "null": true, "~": true}
+)
+
+// yamlString writes s plain when that cannot be misread as another type,
+// and double-quoted otherwise. JSON string escapes are valid YAML.
+func yamlString(s string) string {
+	if plainString.MatchString(s) && !strings.HasSuffix(s, " ") && !reserved[strings.ToLower(s)] {
+		return s
+	}
+	var b bytes.Buffer
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,51 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestYAMLString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Check4Test", "Check4Test"},
+		{"Ion Țurcanu", "Ion Țurcanu"},
+		{"/srv/eusurvey/out", "/srv/eusurvey/out"},
+		{"a.b@example.com", "a.b@example.com"},
+		{"_private", "_private"},
+		{"", `""`},
+		{"yes", `"yes"`},
+		{"No", `"No"`},
+		{"null", `"null"`},
+		{"~", `"~"`},
+		{".inf", `".inf"`},
+		{".NaN", `".NaN"`},
+		{".5", `".5"`},
+		{"-.inf", `"-.inf"`},
+		{"4609", `"4609"`},
+		{"@handle", `"@handle"`},
+		{"trailing ", `"trailing "`},
+		{"key: value", `"key: value"`},
+		{"# comment", `"# comment"`},
+		{"line\nbreak", `"line\nbreak"`},
+		{"<b>&</b>", `"<b>&</b>"`},
+	}
+	for _, tt := range tests {
+		if got := yamlString(tt.in); got != tt.want {
+			t.Errorf("yamlString(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeYAML(t *testing.T) {
+	var b bytes.Buffer
+	v := map[string]any{"alias": "@team", "items": []any{1, "two"}, "empty": []any{}}
+	if err := Encode(&b, YAML, v); err != nil {
+		t.Fatal(err)
+	}
+	want := "alias: \"@team\"\nempty: []\nitems:\n  - 1\n  - two\n"
+	if b.String() != want {
+		t.Errorf("got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
@@ -0,0 +1,73 @@
+package pseudo
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	ModeHash = "hash"
+	ModeDrop = "drop"
+)
+
+// Prefix marks pseudonyms so they are not mistaken for real identities.
+const Prefix = "p-"
+
+// Pseudonymizer replaces identity values (names, emails) with stable keyed
+// pseudonyms or drops them. A nil *Pseudonymizer leaves values unchanged, so
+// writers can call it unconditionally.
+type Pseudonymizer struct {
+	key  []byte
+	drop bool
+}
+
+// New returns a Pseudonymizer for mode "hash" (the default when empty) or
+// "drop". Hash mode requires a non-empty key.
+func New(key, mode string) (*Pseudonymizer, error) {
+	switch mode {
+	case "", ModeHash:
+		if key == "" {
+			return nil, fmt.Errorf("pseudonym_key is required for %q pseudonymisation", ModeHash)
+		}
+		return &Pseudonymizer{key: []byte(key)}, nil
+	case ModeDrop:
+		return &Pseudonymizer{drop: true}, nil
+	default:
+		return nil, fmt.Errorf("unknown pseudonym mode %q (expected %q or %q)", mode, ModeHash, ModeDrop)
+	}
+}
+
+// Apply returns the pseudonym for value. Identities are compared
+// case-insensitively, so "User@Example.com" and "user@example.com" map to
+// the same pseudonym. Empty values stay empty.
+func (p *Pseudonymizer) Apply(value string) string {
+	if p == nil || value == "" {
+		return value
+	}
+	if p.drop {
+		return ""
+	}
+	mac := hmac.New(sha256.New, p.key)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return Prefix + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// NullString applies the pseudonym to a nullable column value.
+func (p *Pseudonymizer) NullString(ns sql.NullString) sql.NullString {
+	if p == nil || !ns.Valid {
+		return ns
+	}
+	return sql.NullString{String: p.Apply(ns.String), Valid: true}
+}
+
+// Matches reports whether value maps to pseudonym.
+func (p *Pseudonymizer) Matches(value, pseudonym string) bool {
+	if p == nil || p.drop || value == "" {
+		return false
+	}
+	return hmac.Equal([]byte(p.Apply(value)), []byte(pseudonym))
+}
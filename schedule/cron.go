@@ -0,0 +1,179 @@
+// Package schedule parses standard 5-field cron expressions.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a parsed cron expression: minute, hour, day of month, month
+// and day of week, each as a set of allowed values.
+type Schedule struct {
+	expr   string
+	minute uint64
+	hour   uint64
+	dom    uint64
+	month  uint64
+	dow    uint64
+	// Standard cron semantics: when both day fields are restricted, a day
+	// matches if either matches.
+	domStar, dowStar bool
+}
+
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{0, 59, nil}
+	hourField   = field{0, 23, nil}
+	domField    = field{1, 31, nil}
+	monthField  = field{1, 12, map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	dowField = field{0, 7, map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// Parse parses "minute hour day-of-month month day-of-week". Each field
+// accepts *, numbers, ranges (1-5), steps (*/15, 1-30/2), lists (1,15) and,
+// for month and day of week, three-letter names. Day of week 7 is Sunday.
+// The @hourly, @daily, @weekly, @monthly and @yearly macros are supported.
+func Parse(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if m, ok := macros[strings.ToLower(spec)]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q: expected 5 fields, got %d", expr, len(fields))
+	}
+	s := &Schedule{expr: expr}
+	var err error
+	parsers := []struct {
+		dst *uint64
+		f   field
+	}{
+		{&s.minute, minuteField}, {&s.hour, hourField}, {&s.dom, domField},
+		{&s.month, monthField}, {&s.dow, dowField},
+	}
+	for i, p := range parsers {
+		if *p.dst, err = parseField(fields[i], p.f); err != nil {
+			return nil, fmt.Errorf("cron expression %q: %w", expr, err)
+		}
+	}
+	// Sunday may be written as 0 or 7.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	return s, nil
+}
+
+func parseField(spec string, f field) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(spec, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			var err error
+			if i := strings.IndexByte(rng, '-'); i >= 0 {
+				if lo, err = f.value(rng[:i]); err != nil {
+					return 0, err
+				}
+				if hi, err = f.value(rng[i+1:]); err != nil {
+					return 0, err
+				}
+			} else {
+				if lo, err = f.value(rng); err != nil {
+					return 0, err
+				}
+				hi = lo
+				if step > 1 {
+					hi = f.max
+				}
+			}
+		}
+		if lo > hi {
+			return 0, fmt.Errorf("invalid range %q", part)
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %q out of range %d-%d", s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the first time after t that matches the schedule, in t's
+// location, or the zero time if none exists within five years (e.g. 31 Feb).
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := s.dom&(1<<uint(t.Day())) != 0
+	dow := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func (s *Schedule) String() string {
+	return s.expr
+}
@@ -0,0 +1,349 @@
+// Package ui is a read-only web dashboard for coordinators: surveys,
+// respondents, their responses and answer PDFs. Templates and assets are
+// embedded in the binary.
+package ui
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"eusurveymgr/analysis"
+	"eusurveymgr/client"
+	"eusurveymgr/config"
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"eusurveymgr/pseudo"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//go:embed templates/*.html static/*
+var content embed.FS
+
+// SessionLifetime is how long a login stays valid.
+const SessionLifetime = 8 * time.Hour
+
+const sessionCookie = "eusurveymgr_session"
+
+// Server serves the dashboard.
+type Server struct {
+	Repo       db.SurveyRepository
+	Client     *client.Client
+	Users      []config.UIUser
+	PDFTimeout int
+	// Pseudonymizer, if set, replaces names and emails on every page.
+	Pseudonymizer *pseudo.Pseudonymizer
+
+	mux       *http.ServeMux
+	templates map[string]*template.Template
+
+	mu       sync.Mutex
+	sessions map[string]session
+	// pdfMu serialises PDF generation through the shared client session.
+	pdfMu sync.Mutex
+}
+
+type session struct {
+	user    string
+	expires time.Time
+}
+
+// New builds the dashboard server.
+func New(repo db.SurveyRepository, c *client.Client, users []config.UIUser) (*Server, error) {
+	s := &Server{
+		Repo:       repo,
+		Client:     c,
+		Users:      users,
+		PDFTimeout: 120,
+		mux:        http.NewServeMux(),
+		templates:  make(map[string]*template.Template),
+		sessions:   make(map[string]session),
+	}
+	funcs := template.FuncMap{"plain": analysis.PlainText}
+	for _, page := range []string{"login", "surveys", "survey", "answer"} {
+		t, err := template.New("layout.html").Funcs(funcs).ParseFS(content, "templates/layout.html", "templates/"+page+".html")
+		if err != nil {
+			return nil, fmt.Errorf("parsing %s template: %w", page, err)
+		}
+		s.templates[page] = t
+	}
+	static, err := fs.Sub(content, "static")
+	if err != nil {
+		return nil, err
+	}
+
+	s.mux.Handle("GET /static/", http.StripPrefix("/static/", http.FileServerFS(static)))
+	s.mux.HandleFunc("GET /login", s.handleLoginForm)
+	s.mux.HandleFunc("POST /login", s.handleLogin)
+	s.mux.HandleFunc("POST /logout", s.handleLogout)
+	s.mux.Handle("GET /{$}", s.auth(s.handleSurveys))
+	s.mux.Handle("GET /surveys/{id}", s.auth(s.handleSurvey))
+	s.mux.Handle("GET /answers/{code}", s.auth(s.handleAnswer))
+	s.mux.Handle("GET /answers/{code}/pdf", s.auth(s.handlePDF))
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	s.mux.ServeHTTP(w, r)
+}
+
+type page struct {
+	Title string
+	User  string
+	Error string
+	// PDF is false when answer PDFs, rendered by EUSurvey with the
+	// respondent's identity, would defeat pseudonymisation.
+	PDF  bool
+	Data any
+}
+
+func (s *Server) render(w http.ResponseWriter, r *http.Request, name string, p page) {
+	p.User = s.currentUser(r)
+	p.PDF = s.Pseudonymizer == nil
+	var buf bytes.Buffer
+	if err := s.templates[name].Execute(&buf, p); err != nil {
+		log.Errorf("UI -- rendering %s: %v", name, err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(buf.Bytes())
+}
+
+func (s *Server) serverError(w http.ResponseWriter, err error) {
+	log.Errorf("UI -- %v", err)
+	http.Error(w, "internal error", http.StatusInternalServerError)
+}
+
+func (s *Server) handleSurveys(w http.ResponseWriter, r *http.Request) {
+	surveys, err := s.Repo.ListSurveys()
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	s.render(w, r, "surveys", page{Title: "Surveys", Data: surveys})
+}
+
+type respondent struct {
+	AnswerSetID int64
+	UniqueCode  string
+	Date        string
+	Name        string
+	Email       string
+}
+
+func (s *Server) handleSurvey(w http.ResponseWriter, r *http.Request) {
+	surveyID, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	surveys, err := s.Repo.ListSurveys()
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	var survey *db.SurveyRow
+	for i := range surveys {
+		if surveys[i].SurveyID == surveyID {
+			survey = &surveys[i]
+		}
+	}
+	if survey == nil {
+		http.NotFound(w, r)
+		return
+	}
+	rows, err := s.Repo.ListAnswerSets(surveyID)
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+	var respondents []respondent
+	for _, a := range rows {
+		respondents = append(respondents, respondent{
+			AnswerSetID: a.AnswerSetID,
+			UniqueCode:  a.UniqueCode,
+			Date:        a.Date.String,
+			Name:        s.Pseudonymizer.NullString(a.Name).String,
+			Email:       s.Pseudonymizer.NullString(a.Email).String,
+		})
+	}
+	s.render(w, r, "survey", page{
+		Title: analysis.PlainText(survey.Title),
+		Data: struct {
+			Survey      *db.SurveyRow
+			Respondents []respondent
+		}{survey, respondents},
+	})
+}
+
+type answerView struct {
+	Set       *db.SubjectAnswerSetRow
+	Name      string
+	Email     string
+	Responses []db.ResponseRow
+}
+
+func (s *Server) handleAnswer(w http.ResponseWriter, r *http.Request) {
+	set, ok := s.answerSet(w, r)
+	if !ok {
+		return
+	}
+	responses, err := s.Repo.GetResponses(set.AnswerSetID)
+	if err != nil {
+		s.serverError(w, err)
+		return
+	}
+
+	// Name and email are the first and last PA_ID=0 rows.
+	view := answerView{Set: set}
+	var identity []int
+	for i, resp := range responses {
+		if resp.PA_ID == 0 && resp.Value.Valid {
+			identity = append(identity, i)
+		}
+	}
+	if len(identity) > 0 {
+		first, last := identity[0], identity[len(identity)-1]
+		view.Name = s.Pseudonymizer.Apply(responses[first].Value.String)
+		view.Email = s.Pseudonymizer.Apply(responses[last].Value.String)
+		responses[first].Value.String = view.Name
+		responses[last].Value.String = view.Email
+	}
+	view.Responses = responses
+	s.render(w, r, "answer", page{Title: set.Alias + " — " + set.UniqueCode, Data: view})
+}
+
+func (s *Server) handlePDF(w http.ResponseWriter, r *http.Request) {
+	if s.Pseudonymizer != nil {
+		http.Error(w, "PDFs are not available with pseudonymisation.", http.StatusForbidden)
+		return
+	}
+	set, ok := s.answerSet(w, r)
+	if !ok {
+		return
+	}
+	s.pdfMu.Lock()
+	data, err := s.Client.GetAnswerPDF(set.UniqueCode, s.PDFTimeout)
+	s.pdfMu.Unlock()
+	if err != nil {
+		log.Errorf("UI -- PDF for %s: %v", set.UniqueCode, err)
+		http.Error(w, "The PDF could not be generated by EUSurvey. Please try again later.", http.StatusBadGateway)
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%d--%s.pdf"`, set.AnswerSetID, set.Alias))
+	w.Write(data)
+}
+
+func (s *Server) answerSet(w http.ResponseWriter, r *http.Request) (*db.SubjectAnswerSetRow, bool) {
+	set, err := s.Repo.GetAnswerSetByCode(r.PathValue("code"))
+	if errors.Is(err, sql.ErrNoRows) {
+		http.NotFound(w, r)
+		return nil, false
+	}
+	if err != nil {
+		s.serverError(w, err)
+		return nil, false
+	}
+	return set, true
+}
+
+func (s *Server) handleLoginForm(w http.ResponseWriter, r *http.Request) {
+	s.render(w, r, "login", page{Title: "Sign in"})
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	username, password := r.PostFormValue("username"), r.PostFormValue("password")
+	if !s.validUser(username, password) {
+		log.Warnf("UI -- failed login for %q from %s", username, r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		s.render(w, r, "login", page{Title: "Sign in", Error: "Wrong username or password."})
+		return
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		s.serverError(w, err)
+		return
+	}
+	id := hex.EncodeToString(token)
+	s.mu.Lock()
+	s.sessions[id] = session{user: username, expires: time.Now().Add(SessionLifetime)}
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteStrictMode,
+		MaxAge:   int(SessionLifetime.Seconds()),
+	})
+	log.Infof("UI -- %s signed in", username)
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if c, err := r.Cookie(sessionCookie); err == nil {
+		s.mu.Lock()
+		delete(s.sessions, c.Value)
+		s.mu.Unlock()
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func (s *Server) validUser(username, password string) bool {
+	valid := false
+	for _, u := range s.Users {
+		userOK := subtle.ConstantTimeCompare([]byte(username), []byte(u.Username)) == 1
+		passOK := subtle.ConstantTimeCompare([]byte(password), []byte(u.Password)) == 1
+		if userOK && passOK && u.Password != "" {
+			valid = true
+		}
+	}
+	return valid
+}
+
+// currentUser returns the user of a valid session cookie, or "".
+func (s *Server) currentUser(r *http.Request) string {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[c.Value]
+	if !ok {
+		return ""
+	}
+	if time.Now().After(sess.expires) {
+		delete(s.sessions, c.Value)
+		return ""
+	}
+	return sess.user
+}
+
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.currentUser(r) == "" {
+			http.Redirect(w, r, "/login", http.StatusSeeOther)
+			return
+		}
+		next(w, r)
+	})
+}
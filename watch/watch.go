@@ -0,0 +1,95 @@
+// Package watch polls a survey for answer sets submitted since the last poll.
+package watch
+
+import (
+	"context"
+	"eusurveymgr/db"
+	"eusurveymgr/log"
+	"eusurveymgr/metrics"
+	"strconv"
+	"time"
+)
+
+// Event is one newly seen answer set.
+type Event struct {
+	SurveyID    int64  `json:"survey_id"`
+	AnswerSetID int64  `json:"answer_set_id"`
+	UniqueCode  string `json:"uniquecode"`
+	Date        string `json:"date"`
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+}
+
+// NewEvent converts an answer set row of the given survey.
+func NewEvent(surveyID int64, a db.AnswerSetRow) Event {
+	return Event{
+		SurveyID:    surveyID,
+		AnswerSetID: a.AnswerSetID,
+		UniqueCode:  a.UniqueCode,
+		Date:        a.Date.String,
+		Name:        a.Name.String,
+		Email:       a.Email.String,
+	}
+}
+
+// Watcher remembers the highest ANSWER_SET_ID seen for a survey and reports
+// every answer set above it exactly once.
+type Watcher struct {
+	Repo     db.SurveyRepository
+	SurveyID int64
+	Interval time.Duration
+	// LastID is the highest ANSWER_SET_ID already reported. Set it before
+	// Run to resume from a known position; 0 reports every answer set.
+	LastID int64
+	// Uncounted leaves the new responses metric to the caller, e.g. when
+	// several watchers poll the same survey.
+	Uncounted bool
+
+	polled bool
+}
+
+// Poll returns the answer sets submitted since the previous poll, oldest
+// first, and advances LastID past them. Answer sets returned by a first
+// poll from LastID 0 already existed, so they are not counted as new
+// responses in the metrics.
+func (w *Watcher) Poll() ([]db.AnswerSetRow, error) {
+	answers, err := w.Repo.ListAnswerSetsAfter(w.SurveyID, w.LastID)
+	if err != nil {
+		return nil, err
+	}
+	if !w.Uncounted && (w.polled || w.LastID > 0) && len(answers) > 0 {
+		metrics.NewResponses.Add(float64(len(answers)), strconv.FormatInt(w.SurveyID, 10))
+	}
+	w.polled = true
+	for _, a := range answers {
+		if a.AnswerSetID > w.LastID {
+			w.LastID = a.AnswerSetID
+		}
+	}
+	return answers, nil
+}
+
+// Run polls every Interval until ctx is cancelled and calls fn for each new
+// answer set. Poll errors are logged and retried on the next tick, so a
+// database restart does not end a long-running watch; an error from fn
+// stops it.
+func (w *Watcher) Run(ctx context.Context, fn func(db.AnswerSetRow) error) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		answers, err := w.Poll()
+		if err != nil {
+			log.Warnf("WATCH -- survey %d: %v", w.SurveyID, err)
+		}
+		for _, a := range answers {
+			if err := fn(a); err != nil {
+				return err
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}